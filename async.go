@@ -0,0 +1,97 @@
+package mempot
+
+import "time"
+
+// asyncBufferSize is the capacity of the channel holding the writes of AsyncSet which have not been applied yet.
+const asyncBufferSize = 1024
+
+// asyncWrite is a write enqueued by AsyncSet.
+type asyncWrite[K comparable, T any] struct {
+	key  K
+	item Item[T]
+	ttl  time.Duration
+}
+
+// AsyncSet will add an Item to the Cache with the default time-to-live without waiting for the lock of the Cache.
+// The write is enqueued and applied by a background goroutine, so reads may not reflect it immediately.
+// Writes of AsyncSet are applied in order, but may overwrite Items which have been set synchronously in between.
+// If the queue is full, AsyncSet blocks until there is space again. Close waits until all enqueued writes have
+// been applied. After Close or if the context of the Cache has been canceled, the write is applied synchronously.
+func (c *Cache[K, T]) AsyncSet(key K, value T) {
+	ttl := c.DefaultTTL()
+	w := asyncWrite[K, T]{key: key, item: c.newItem(value, ttl), ttl: ttl}
+
+	c.asyncOnce.Do(func() {
+		c.writes = make(chan asyncWrite[K, T], asyncBufferSize)
+		c.asyncDone = make(chan struct{})
+		go c.runWrites()
+	})
+
+	c.asyncMut.RLock()
+	defer c.asyncMut.RUnlock()
+
+	if !c.asyncStopped {
+		select {
+		case c.writes <- w:
+			return
+		case <-c.closed:
+		case <-c.ctx.Done():
+		}
+	}
+
+	c.apply([]asyncWrite[K, T]{w})
+}
+
+// runWrites applies the writes enqueued by AsyncSet until the Cache is closed or its context is canceled.
+// The remaining writes are applied before asyncDone is closed.
+func (c *Cache[K, T]) runWrites() {
+	defer close(c.asyncDone)
+
+	for {
+		select {
+		case w := <-c.writes:
+			// apply all writes which are already waiting while acquiring the lock only once
+			batch := []asyncWrite[K, T]{w}
+			for range len(c.writes) {
+				batch = append(batch, <-c.writes)
+			}
+
+			c.apply(batch)
+		case <-c.closed:
+			c.drainWrites()
+			return
+		case <-c.ctx.Done():
+			c.drainWrites()
+			return
+		}
+	}
+}
+
+// drainWrites stops accepting writes from AsyncSet and applies the remaining ones.
+func (c *Cache[K, T]) drainWrites() {
+	// waits until no AsyncSet is sending anymore, which is guaranteed to finish
+	// because the closed channel or the canceled context is selected instead
+	c.asyncMut.Lock()
+	c.asyncStopped = true
+	c.asyncMut.Unlock()
+
+	batch := make([]asyncWrite[K, T], 0, len(c.writes))
+	for range len(c.writes) {
+		batch = append(batch, <-c.writes)
+	}
+
+	c.apply(batch)
+}
+
+// apply stores the writes in the Cache.
+func (c *Cache[K, T]) apply(batch []asyncWrite[K, T]) {
+	var evicted []eviction[K, T]
+
+	c.mut.Lock()
+	for _, w := range batch {
+		evicted = append(evicted, c.set(w.key, w.item, w.ttl)...)
+	}
+	c.mut.Unlock()
+
+	c.notifyEvicted(evicted)
+}
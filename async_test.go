@@ -0,0 +1,75 @@
+package mempot
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCacheAsyncSet(t *testing.T) {
+	cache, cancel := setupCache(1, 1)
+	defer cancel()
+
+	if cache.writes != nil {
+		t.Error("buffer of AsyncSet has been allocated before the first call")
+	}
+
+	cache.AsyncSet(key, data)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if item, ok := cache.Get(key); ok {
+			if item.Data != data {
+				t.Errorf("got %q, want %q", item.Data, data)
+			}
+
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("item has not been set by AsyncSet")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCacheAsyncSetClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[int, int](ctx, Config{CleanupInterval: time.Hour})
+
+	const n = asyncBufferSize * 2
+
+	for i := range n {
+		cache.AsyncSet(i, i)
+	}
+
+	cache.Close()
+
+	if l := cache.Len(); l != n {
+		t.Errorf("got %d items after Close, want %d", l, n)
+	}
+
+	// writes after Close are applied synchronously
+	cache.AsyncSet(n, n)
+
+	if _, ok := cache.Get(n); !ok {
+		t.Error("item set by AsyncSet after Close not found")
+	}
+}
+
+func TestCacheAsyncSetCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cache := NewCache[string, string](ctx, Config{CleanupInterval: time.Hour})
+
+	cancel()
+	cache.AsyncSet(key, data)
+	cache.Close()
+
+	if _, ok := cache.Get(key); !ok {
+		t.Error("item set by AsyncSet not found after the context has been canceled")
+	}
+}
@@ -0,0 +1,30 @@
+package mempot
+
+// Increment adds delta to the value of the Item and stores the result with the default time-to-live.
+// If the Item was not found or has been expired, its value is treated as 0.
+// The new value is returned. Overflows wrap around like regular int64 arithmetic.
+func Increment[K comparable](c *Cache[K, int64], key K, delta int64) int64 {
+	c.mut.Lock()
+
+	var value int64
+	if item, ok := c.lookup(key, false); ok {
+		value = item.Data
+	}
+
+	value += delta
+
+	ttl := c.DefaultTTL()
+	evicted := c.set(key, c.newItem(value, ttl), ttl)
+	c.mut.Unlock()
+
+	c.notifyEvicted(evicted)
+
+	return value
+}
+
+// Decrement subtracts delta from the value of the Item and stores the result with the default time-to-live.
+// If the Item was not found or has been expired, its value is treated as 0.
+// The new value is returned. Overflows wrap around like regular int64 arithmetic.
+func Decrement[K comparable](c *Cache[K, int64], key K, delta int64) int64 {
+	return Increment(c, key, -delta)
+}
@@ -0,0 +1,75 @@
+package mempot
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func setupCounterCache() (*Cache[string, int64], *fakeClock, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	clock := &fakeClock{now: time.Now()}
+
+	cache := NewCache[string, int64](ctx, Config{DefaultTTL: time.Second, CleanupInterval: time.Hour, Clock: clock})
+
+	return cache, clock, cancel
+}
+
+func TestIncrementDecrement(t *testing.T) {
+	cache, clock, cancel := setupCounterCache()
+	defer cancel()
+
+	if v := Increment(cache, key, 5); v != 5 {
+		t.Errorf("got %d, want %d", v, 5)
+	}
+
+	if v := Decrement(cache, key, 2); v != 3 {
+		t.Errorf("got %d, want %d", v, 3)
+	}
+
+	clock.Advance(time.Second * 2)
+
+	// expired counters start again at 0
+	if v := Increment(cache, key, 1); v != 1 {
+		t.Errorf("got %d, want %d", v, 1)
+	}
+
+	cache.Set("max", math.MaxInt64)
+
+	if v := Increment(cache, "max", 1); v != math.MinInt64 {
+		t.Errorf("got %d, want %d", v, int64(math.MinInt64))
+	}
+}
+
+func TestIncrementConcurrent(t *testing.T) {
+	cache, _, cancel := setupCounterCache()
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for range 100 {
+				Increment(cache, key, 1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	item, ok := cache.Get(key)
+	if !ok {
+		t.Fatal("item not found")
+	}
+
+	if item.Data != 1000 {
+		t.Errorf("got %d, want %d", item.Data, 1000)
+	}
+}
@@ -0,0 +1,124 @@
+package mempot
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// EventType describes the kind of change to an Item.
+type EventType int
+
+const (
+	// EventSet is published when an Item is added or updated.
+	EventSet EventType = iota
+
+	// EventDelete is published when an Item is removed by Delete, DeleteMany, DeleteFunc, GetAndDelete,
+	// InvalidateBefore or Tx.Delete.
+	EventDelete
+
+	// EventExpire is published when an expired Item is removed by a cleanup, by Get or GetOrdered
+	// if LazyExpiration is enabled or by GetAndDelete.
+	EventExpire
+
+	// EventEvict is published when an Item is evicted because MaxItems or MaxBytes was exceeded
+	// or by CloseAndEvict.
+	EventEvict
+)
+
+// String returns the name of the EventType.
+func (t EventType) String() string {
+	switch t {
+	case EventSet:
+		return "set"
+	case EventDelete:
+		return "delete"
+	case EventExpire:
+		return "expire"
+	case EventEvict:
+		return "evict"
+	}
+
+	return "unknown"
+}
+
+// Event describes a change to the Item with the Key.
+type Event[K comparable] struct {
+	Type EventType
+	Key  K
+
+	// Time is the time the change happened according to the Clock of the Cache.
+	Time time.Time
+}
+
+// eventBufferSize is the capacity of the channel of each subscriber.
+const eventBufferSize = 128
+
+// Subscribe returns a channel which receives an Event for every change to the Items of the Cache and a function
+// to unsubscribe, which closes the channel. Every subscriber receives its own channel.
+// Events are published without blocking the Cache, so if the buffer of a channel is full because the subscriber
+// does not keep up, further Events are dropped for this subscriber until there is space again.
+// No Events are published for Reset.
+func (c *Cache[K, T]) Subscribe() (<-chan Event[K], func()) {
+	ch := make(chan Event[K], eventBufferSize)
+
+	c.mut.Lock()
+	if c.subscribers == nil {
+		c.subscribers = make(map[chan Event[K]]struct{})
+	}
+	c.subscribers[ch] = struct{}{}
+	c.mut.Unlock()
+
+	var once sync.Once
+
+	unsubscribe := func() {
+		once.Do(func() {
+			c.mut.Lock()
+			delete(c.subscribers, ch)
+			close(ch)
+			c.mut.Unlock()
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// RecentEvents returns the last EventHistorySize Events ordered from the oldest to the newest.
+// Nil is returned if EventHistorySize is 0.
+func (c *Cache[K, T]) RecentEvents() []Event[K] {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	if c.history == nil {
+		return nil
+	}
+
+	if !c.historyFull {
+		return slices.Clone(c.history[:c.historyNext])
+	}
+
+	return slices.Concat(c.history[c.historyNext:], c.history[:c.historyNext])
+}
+
+// publish sends the Event to all subscribers without blocking and adds it to the history.
+// The caller must hold the write lock.
+func (c *Cache[K, T]) publish(typ EventType, key K) {
+	if len(c.subscribers) == 0 && c.history == nil {
+		return
+	}
+
+	event := Event[K]{Type: typ, Key: key, Time: c.now()}
+
+	for ch := range c.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+
+	if c.history != nil {
+		c.history[c.historyNext] = event
+		c.historyNext = (c.historyNext + 1) % len(c.history)
+		c.historyFull = c.historyFull || c.historyNext == 0
+	}
+}
@@ -0,0 +1,195 @@
+package mempot
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func receiveEvents(t *testing.T, ch <-chan Event[string], n int) []Event[string] {
+	t.Helper()
+
+	events := make([]Event[string], 0, n)
+
+	for range n {
+		select {
+		case e := <-ch:
+			events = append(events, e)
+		case <-time.After(time.Second):
+			t.Fatalf("got %d events, want %d", len(events), n)
+		}
+	}
+
+	return events
+}
+
+// sameEvent returns true if both Events have the same type and key, ignoring their time.
+func sameEvent(a, b Event[string]) bool {
+	return a.Type == b.Type && a.Key == b.Key
+}
+
+func TestCacheSubscribe(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour, MaxItems: 2})
+	defer cancel()
+
+	events, unsubscribe := cache.Subscribe()
+	defer unsubscribe()
+
+	cache.SetWithTTL("a", data, time.Millisecond*50)
+	cache.Set("b", data)
+	cache.Set("c", data)
+	cache.Delete("b")
+	cache.Delete("missing")
+	cache.Set("d", data)
+
+	clock.Advance(time.Millisecond * 100)
+	cache.Cleanup()
+
+	want := []Event[string]{
+		{Type: EventSet, Key: "a"},
+		{Type: EventSet, Key: "b"},
+		{Type: EventSet, Key: "c"},
+		{Type: EventEvict, Key: "a"},
+		{Type: EventDelete, Key: "b"},
+		{Type: EventSet, Key: "d"},
+	}
+
+	got := receiveEvents(t, events, len(want))
+	for i := range want {
+		if !sameEvent(got[i], want[i]) {
+			t.Errorf("got event %d %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	cache.SetWithTTL("e", data, time.Millisecond*50)
+	clock.Advance(time.Millisecond * 100)
+	cache.Cleanup()
+
+	got = receiveEvents(t, events, 3)
+	if !sameEvent(got[2], Event[string]{Type: EventExpire, Key: "e"}) {
+		t.Errorf("got %+v, want expire event for %s", got[2], "e")
+	}
+}
+
+func TestCacheSubscribeMultiple(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	first, unsubscribeFirst := cache.Subscribe()
+	second, unsubscribeSecond := cache.Subscribe()
+	defer unsubscribeSecond()
+
+	cache.Set(key, data)
+
+	for _, ch := range []<-chan Event[string]{first, second} {
+		if e := receiveEvents(t, ch, 1)[0]; !sameEvent(e, Event[string]{Type: EventSet, Key: key}) {
+			t.Errorf("got %+v, want set event for %s", e, key)
+		}
+	}
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+
+	if _, ok := <-first; ok {
+		t.Error("channel has not been closed")
+	}
+
+	cache.Delete(key)
+
+	if e := receiveEvents(t, second, 1)[0]; e.Type != EventDelete {
+		t.Errorf("got %s, want %s", e.Type, EventDelete)
+	}
+}
+
+func TestCacheSubscribeOverflow(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	events, unsubscribe := cache.Subscribe()
+	defer unsubscribe()
+
+	ctx, cancelSet := context.WithTimeout(context.Background(), time.Second)
+	defer cancelSet()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for range eventBufferSize * 2 {
+			cache.Set(key, data)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatal("set blocked on a full subscriber")
+	}
+
+	if n := len(events); n != eventBufferSize {
+		t.Errorf("got %d buffered events, want %d", n, eventBufferSize)
+	}
+}
+
+func TestCacheRecentEvents(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour, EventHistorySize: 3})
+	defer cancel()
+
+	if events := cache.RecentEvents(); len(events) != 0 {
+		t.Errorf("got %v, want no events", events)
+	}
+
+	cache.Set("a", data)
+	clock.Advance(time.Second)
+	cache.Delete("a")
+
+	events := cache.RecentEvents()
+	if len(events) != 2 || !sameEvent(events[0], Event[string]{Type: EventSet, Key: "a"}) || !sameEvent(events[1], Event[string]{Type: EventDelete, Key: "a"}) {
+		t.Errorf("got %v, want set and delete of %s", events, "a")
+	}
+
+	if !events[1].Time.Equal(clock.Now()) || !events[0].Time.Before(events[1].Time) {
+		t.Errorf("got times %s and %s, want %s for the last event", events[0].Time, events[1].Time, clock.Now())
+	}
+
+	cache.SetWithTTL("b", data, time.Millisecond*50)
+	cache.Set("c", data)
+	clock.Advance(time.Millisecond * 100)
+	cache.Cleanup()
+
+	want := []Event[string]{
+		{Type: EventSet, Key: "b"},
+		{Type: EventSet, Key: "c"},
+		{Type: EventExpire, Key: "b"},
+	}
+
+	events = cache.RecentEvents()
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+
+	for i := range want {
+		if !sameEvent(events[i], want[i]) {
+			t.Errorf("got event %d %+v, want %+v", i, events[i], want[i])
+		}
+	}
+
+	// the returned slice is a copy
+	events[0].Key = "changed"
+
+	if cache.RecentEvents()[0].Key != "b" {
+		t.Error("history has been modified")
+	}
+}
+
+func TestCacheRecentEventsDisabled(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	cache.Set(key, data)
+
+	if events := cache.RecentEvents(); events != nil {
+		t.Errorf("got %v, want nil", events)
+	}
+}
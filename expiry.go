@@ -0,0 +1,91 @@
+package mempot
+
+import "container/heap"
+
+// expiryHeap is a min-heap of entries ordered by their expiration time.
+// Entries which do not expire are not part of the heap.
+type expiryHeap[K comparable, T any] []*entry[K, T]
+
+func (h expiryHeap[K, T]) Len() int {
+	return len(h)
+}
+
+func (h expiryHeap[K, T]) Less(i, j int) bool {
+	return h[i].item.TTL < h[j].item.TTL
+}
+
+func (h expiryHeap[K, T]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap[K, T]) Push(x any) {
+	e := x.(*entry[K, T])
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *expiryHeap[K, T]) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+	*h = old[:n-1]
+
+	return e
+}
+
+// before returns all entries which expire before ttl in no particular order.
+// Subtrees of the heap whose root expires later are not visited.
+func (h expiryHeap[K, T]) before(ttl int64) []*entry[K, T] {
+	var entries []*entry[K, T]
+
+	var visit func(i int)
+	visit = func(i int) {
+		if i >= len(h) || h[i].item.TTL >= ttl {
+			return
+		}
+
+		entries = append(entries, h[i])
+
+		visit(2*i + 1)
+		visit(2*i + 2)
+	}
+
+	visit(0)
+
+	return entries
+}
+
+// updateExpiry adds, moves or removes the entry in the expiry heap after its expiration time has changed.
+// The caller must hold the write lock.
+func (c *Cache[K, T]) updateExpiry(e *entry[K, T]) {
+	switch {
+	case e.item.TTL == 0 && e.index >= 0:
+		heap.Remove(&c.expiries, e.index)
+	case e.item.TTL == 0:
+		// entries which do not expire are not tracked
+	case e.index >= 0:
+		heap.Fix(&c.expiries, e.index)
+	default:
+		heap.Push(&c.expiries, e)
+	}
+
+	if e.index == 0 {
+		// the cleanup goroutine has to wake up earlier
+		select {
+		case c.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// removeExpiry removes the entry from the expiry heap.
+// The caller must hold the write lock.
+func (c *Cache[K, T]) removeExpiry(e *entry[K, T]) {
+	if e.index >= 0 {
+		heap.Remove(&c.expiries, e.index)
+	}
+}
@@ -0,0 +1,127 @@
+package mempot
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func BenchmarkCleanupMostlyUnexpired(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := &fakeClock{now: time.Now()}
+
+	cache := NewCache[int, int](ctx, Config{CleanupInterval: time.Hour, Clock: clock})
+
+	for i := range 100_000 {
+		cache.SetWithTTL(i, i, time.Hour)
+	}
+
+	b.ResetTimer()
+
+	for i := range b.N {
+		cache.SetWithTTL(-i-1, i, time.Millisecond)
+		clock.Advance(time.Millisecond * 2)
+		cache.Cleanup()
+	}
+}
+
+func TestCacheExpiryHeap(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour, SlidingExpiration: true})
+	defer cancel()
+
+	cache.SetWithTTL("a", data, time.Millisecond*300)
+	cache.SetWithTTL("b", data, time.Millisecond*100)
+	cache.SetWithTTL("c", data, time.Millisecond*200)
+	cache.SetWithTTL("d", data, 0)
+	cache.SetWithTTL("e", data, time.Millisecond*100)
+
+	if n := len(cache.expiries); n != 4 {
+		t.Errorf("got %d tracked expiries, want %d", n, 4)
+	}
+
+	// replacing an expiring item with a non-expiring one removes it from the heap
+	cache.SetWithTTL("e", data, 0)
+	cache.Delete("c")
+
+	if n := len(cache.expiries); n != 2 {
+		t.Errorf("got %d tracked expiries, want %d", n, 2)
+	}
+
+	clock.Advance(time.Millisecond * 50)
+
+	// sliding expiration and touching reorder the heap
+	cache.Get("b")
+	cache.Touch("a", time.Millisecond*20)
+
+	clock.Advance(time.Millisecond * 90)
+	cache.Cleanup()
+
+	for _, k := range []string{"b", "d", "e"} {
+		if !cache.Exists(k) {
+			t.Errorf("item %s not found", k)
+		}
+	}
+
+	if cache.Exists("a") {
+		t.Error("expired item still exists")
+	}
+
+	for i, e := range cache.expiries {
+		if e.index != i {
+			t.Errorf("got index %d, want %d", e.index, i)
+		}
+	}
+}
+
+func BenchmarkCleanupBatchSize(b *testing.B) {
+	for _, size := range []int{0, 1000} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			clock := &fakeClock{now: time.Now()}
+
+			cache := NewCache[int, int](ctx, Config{CleanupInterval: time.Hour, CleanupBatchSize: size, Clock: clock})
+
+			var maxWait time.Duration
+
+			for range b.N {
+				b.StopTimer()
+				for i := range 100_000 {
+					cache.SetWithTTL(i, i, time.Millisecond)
+				}
+				clock.Advance(time.Millisecond * 2)
+				b.StartTimer()
+
+				done := make(chan struct{})
+
+				go func() {
+					defer close(done)
+					cache.Cleanup()
+				}()
+
+				// measure how long a concurrent read is blocked by the cleanup
+			loop:
+				for {
+					select {
+					case <-done:
+						break loop
+					default:
+					}
+
+					start := time.Now()
+					cache.Peek(0)
+					maxWait = max(maxWait, time.Since(start))
+
+					runtime.Gosched()
+				}
+			}
+
+			b.ReportMetric(float64(maxWait.Microseconds()), "max-wait-µs")
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package mempot
+
+// Map calls fn for every Item in the Cache which has not been expired and returns the results
+// in no particular order. The read lock is held while fn is called, so fn must not modify the Cache.
+func Map[K comparable, T, R any](c *Cache[K, T], fn func(key K, value T) R) []R {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	results := make([]R, 0, len(c.data))
+
+	for key, e := range c.data {
+		if c.expired(&e.item) {
+			continue
+		}
+
+		results = append(results, fn(key, e.item.Data))
+	}
+
+	return results
+}
+
+// Filter returns the data of all Items in the Cache which have not been expired and for which pred returns true
+// by their key. The read lock is held while pred is called, so pred must not modify the Cache.
+func Filter[K comparable, T any](c *Cache[K, T], pred func(key K, value T) bool) map[K]T {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	results := make(map[K]T)
+
+	for key, e := range c.data {
+		if c.expired(&e.item) || !pred(key, e.item.Data) {
+			continue
+		}
+
+		results[key] = e.item.Data
+	}
+
+	return results
+}
+
+// GroupBy returns the data of all Items in the Cache which have not been expired grouped by the key
+// returned by keyFn. The order of the data within a group is not defined.
+// The read lock is held while keyFn is called, so keyFn must not modify the Cache.
+func GroupBy[K comparable, T any, G comparable](c *Cache[K, T], keyFn func(value T) G) map[G][]T {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	groups := make(map[G][]T)
+
+	for _, e := range c.data {
+		if c.expired(&e.item) {
+			continue
+		}
+
+		group := keyFn(e.item.Data)
+		groups[group] = append(groups[group], e.item.Data)
+	}
+
+	return groups
+}
+
+// CompareAndSwap replaces the data of the Item with newValue if the Item was found in the Cache,
+// has not been expired and its data equals oldValue. The expiration of the Item is preserved.
+// True is returned if the data has been swapped. The comparison and the swap happen atomically.
+func CompareAndSwap[K, T comparable](c *Cache[K, T], key K, oldValue, newValue T) bool {
+	c.mut.Lock()
+
+	e, ok := c.data[key]
+	if !ok || c.expired(&e.item) || e.item.Data != oldValue {
+		c.mut.Unlock()
+		return false
+	}
+
+	item := e.item
+	item.Data = newValue
+
+	evicted := c.set(key, item, e.ttl)
+	c.mut.Unlock()
+
+	c.notifyEvicted(evicted)
+
+	return true
+}
+
+// DeleteFunc removes all Items from the Cache whose key satisfies pred, e.g. all keys with a common prefix.
+// Expired Items which have not been removed by a cleanup yet are considered as well.
+// The write lock is held while pred is called, so pred must not access the Cache.
+func DeleteFunc[K comparable, T any](c *Cache[K, T], pred func(key K) bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	var keys []K
+
+	for key := range c.data {
+		if pred(key) {
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range keys {
+		c.remove(key)
+		c.publish(EventDelete, key)
+	}
+}
@@ -0,0 +1,151 @@
+package mempot
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMap(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetWithTTL("a", "1", time.Minute)
+	cache.SetWithTTL("b", "22", time.Minute)
+	cache.SetWithTTL("c", "333", time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	lengths := Map(cache, func(key string, value string) int {
+		return len(value)
+	})
+	slices.Sort(lengths)
+
+	if !slices.Equal(lengths, []int{1, 2}) {
+		t.Errorf("got %v, want %v", lengths, []int{1, 2})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetWithTTL("a", "1", time.Minute)
+	cache.SetWithTTL("b", "22", time.Minute)
+	cache.SetWithTTL("c", "333", time.Minute)
+	cache.SetWithTTL("d", "4444", time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	results := Filter(cache, func(key string, value string) bool {
+		return len(value) > 1
+	})
+
+	want := map[string]string{"b": "22", "c": "333"}
+	if !maps.Equal(results, want) {
+		t.Errorf("got %v, want %v", results, want)
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	type product struct {
+		name     string
+		category string
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := &fakeClock{now: time.Now()}
+	cache := NewCache[int, product](ctx, Config{CleanupInterval: time.Hour, Clock: clock})
+
+	cache.SetWithTTL(1, product{name: "apple", category: "fruit"}, time.Minute)
+	cache.SetWithTTL(2, product{name: "carrot", category: "vegetable"}, time.Minute)
+	cache.SetWithTTL(3, product{name: "banana", category: "fruit"}, time.Minute)
+	cache.SetWithTTL(4, product{name: "cherry", category: "fruit"}, time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	groups := GroupBy(cache, func(value product) string {
+		return value.category
+	})
+
+	names := make(map[string][]string, len(groups))
+	for category, products := range groups {
+		for _, p := range products {
+			names[category] = append(names[category], p.name)
+		}
+
+		slices.Sort(names[category])
+	}
+
+	want := map[string][]string{
+		"fruit":     {"apple", "banana"},
+		"vegetable": {"carrot"},
+	}
+
+	if !maps.EqualFunc(names, want, slices.Equal) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	if CompareAndSwap(cache, key, data, "baz") {
+		t.Error("missing item has been swapped")
+	}
+
+	cache.SetWithTTL(key, data, time.Minute)
+	before, _ := cache.Get(key)
+
+	if !CompareAndSwap(cache, key, data, "baz") {
+		t.Error("matching item has not been swapped")
+	}
+
+	// the value has changed underneath
+	if CompareAndSwap(cache, key, data, "qux") {
+		t.Error("changed item has been swapped")
+	}
+
+	item, _ := cache.Get(key)
+	if item.Data != "baz" {
+		t.Errorf("got %s, want %s", item.Data, "baz")
+	}
+
+	if item.TTL != before.TTL {
+		t.Errorf("got %d, want %d", item.TTL, before.TTL)
+	}
+
+	clock.Advance(time.Minute * 2)
+
+	if CompareAndSwap(cache, key, "baz", "qux") {
+		t.Error("expired item has been swapped")
+	}
+}
+
+func TestDeleteFunc(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	cache.Set("user:123:profile", data)
+	cache.Set("user:123:settings", data)
+	cache.Set("user:1234:profile", data)
+	cache.Set("group:123", data)
+
+	DeleteFunc(cache, func(key string) bool {
+		return strings.HasPrefix(key, "user:123:")
+	})
+
+	keys := cache.Keys()
+	slices.Sort(keys)
+
+	want := []string{"group:123", "user:1234:profile"}
+	if !slices.Equal(keys, want) {
+		t.Errorf("got %v, want %v", keys, want)
+	}
+}
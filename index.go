@@ -0,0 +1,106 @@
+package mempot
+
+// secondaryIndex maps an attribute extracted from the data of the Items to their keys.
+// The attribute of each key is stored when the Item is added, so it can be removed even if the data
+// has been modified in place, e.g. through a pointer, and would extract a different attribute now.
+type secondaryIndex[K comparable, T any] struct {
+	extract func(value T) string
+	keys    map[string]map[K]struct{}
+	attrs   map[K]string
+}
+
+func newSecondaryIndex[K comparable, T any](extract func(value T) string) *secondaryIndex[K, T] {
+	return &secondaryIndex[K, T]{extract: extract, keys: make(map[string]map[K]struct{}), attrs: make(map[K]string)}
+}
+
+func (idx *secondaryIndex[K, T]) add(key K, value T) {
+	idx.remove(key)
+
+	attr := idx.extract(value)
+
+	keys, ok := idx.keys[attr]
+	if !ok {
+		keys = make(map[K]struct{})
+		idx.keys[attr] = keys
+	}
+
+	keys[key] = struct{}{}
+	idx.attrs[key] = attr
+}
+
+func (idx *secondaryIndex[K, T]) remove(key K) {
+	attr, ok := idx.attrs[key]
+	if !ok {
+		return
+	}
+
+	delete(idx.attrs, key)
+	delete(idx.keys[attr], key)
+
+	if len(idx.keys[attr]) == 0 {
+		delete(idx.keys, attr)
+	}
+}
+
+// AddIndex adds a secondary index with the given name, which allows to look up Items by an attribute of their data
+// with GetByIndex, e.g. a session by its user ID if the Cache is keyed by the session token.
+// The index is built from the Items in the Cache and kept up to date whenever an Item is set or removed.
+// An existing index with the same name is replaced. extract is called while holding the write lock,
+// so it must not access the Cache.
+func (c *Cache[K, T]) AddIndex(name string, extract func(value T) string) {
+	idx := newSecondaryIndex[K, T](extract)
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	for key, e := range c.data {
+		idx.add(key, e.item.Data)
+	}
+
+	if c.indexes == nil {
+		c.indexes = make(map[string]*secondaryIndex[K, T])
+	}
+
+	c.indexes[name] = idx
+}
+
+// GetByIndex returns all Items which have not been expired and whose attribute extracted by the index
+// with the given name equals attr, in no particular order. Nil is returned if no such index has been added.
+func (c *Cache[K, T]) GetByIndex(name, attr string) []Item[T] {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	idx, ok := c.indexes[name]
+	if !ok {
+		return nil
+	}
+
+	items := make([]Item[T], 0, len(idx.keys[attr]))
+
+	for key := range idx.keys[attr] {
+		e, ok := c.data[key]
+		if !ok || c.expired(&e.item) {
+			continue
+		}
+
+		items = append(items, c.copied(e.item))
+	}
+
+	return items
+}
+
+// indexAdd adds the Item to all secondary indexes.
+// The caller must hold the write lock.
+func (c *Cache[K, T]) indexAdd(key K, value T) {
+	for _, idx := range c.indexes {
+		idx.add(key, value)
+	}
+}
+
+// indexRemove removes the Item from all secondary indexes.
+// The caller must hold the write lock.
+func (c *Cache[K, T]) indexRemove(key K) {
+	for _, idx := range c.indexes {
+		idx.remove(key)
+	}
+}
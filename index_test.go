@@ -0,0 +1,172 @@
+package mempot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type session struct {
+	token  string
+	userID string
+}
+
+func sessionTokens(items []Item[session]) []string {
+	tokens := make([]string, 0, len(items))
+	for _, item := range items {
+		tokens = append(tokens, item.Data.token)
+	}
+
+	slices.Sort(tokens)
+
+	return tokens
+}
+
+func TestCacheIndex(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := &fakeClock{now: time.Now()}
+	cache := NewCache[string, session](ctx, Config{CleanupInterval: time.Hour, Clock: clock})
+
+	// Items set before the index is added are indexed as well
+	cache.Set("t1", session{token: "t1", userID: "alice"})
+
+	cache.AddIndex("user", func(value session) string {
+		return value.userID
+	})
+
+	cache.Set("t2", session{token: "t2", userID: "alice"})
+	cache.Set("t3", session{token: "t3", userID: "bob"})
+	cache.SetWithTTL("t4", session{token: "t4", userID: "alice"}, time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	if got := sessionTokens(cache.GetByIndex("user", "alice")); !slices.Equal(got, []string{"t1", "t2"}) {
+		t.Errorf("got %v, want %v", got, []string{"t1", "t2"})
+	}
+
+	cache.Delete("t1")
+	cache.Set("t2", session{token: "t2", userID: "bob"})
+
+	if got := sessionTokens(cache.GetByIndex("user", "alice")); len(got) != 0 {
+		t.Errorf("got %v, want no sessions", got)
+	}
+
+	if got := sessionTokens(cache.GetByIndex("user", "bob")); !slices.Equal(got, []string{"t2", "t3"}) {
+		t.Errorf("got %v, want %v", got, []string{"t2", "t3"})
+	}
+
+	cache.Cleanup()
+
+	if keys := cache.indexes["user"].keys["alice"]; len(keys) != 0 {
+		t.Errorf("expired item is still indexed: %v", keys)
+	}
+
+	if items := cache.GetByIndex("missing", "bob"); items != nil {
+		t.Errorf("got %v for a missing index, want nil", items)
+	}
+}
+
+func TestCacheIndexMutatedPointer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[string, *session](ctx, Config{CleanupInterval: time.Hour})
+
+	cache.AddIndex("user", func(value *session) string {
+		return value.userID
+	})
+
+	cache.Set("t1", &session{token: "t1", userID: "alice"})
+
+	// the attribute changes in place, so extracting it again returns the new one
+	cache.Update("t1", func(old *session) *session {
+		old.userID = "bob"
+		return old
+	})
+
+	if items := cache.GetByIndex("user", "alice"); len(items) != 0 {
+		t.Errorf("got %d items for the old attribute, want %d", len(items), 0)
+	}
+
+	if items := cache.GetByIndex("user", "bob"); len(items) != 1 {
+		t.Errorf("got %d items for the new attribute, want %d", len(items), 1)
+	}
+
+	// mutating the cached value without a write leaves the stored attribute, which must be removed on Delete
+	cache.Set("t2", &session{token: "t2", userID: "alice"})
+	item, _ := cache.Get("t2")
+	item.Data.userID = "carol"
+
+	cache.Delete("t1")
+	cache.Delete("t2")
+
+	for _, attr := range []string{"alice", "bob", "carol"} {
+		if items := cache.GetByIndex("user", attr); len(items) != 0 {
+			t.Errorf("got %d items for %q after Delete, want %d", len(items), attr, 0)
+		}
+	}
+
+	if n := len(cache.indexes["user"].keys); n != 0 {
+		t.Errorf("got %d indexed attributes after Delete, want %d", n, 0)
+	}
+}
+
+func TestCacheIndexConcurrent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[string, session](ctx, Config{CleanupInterval: time.Hour, MaxItems: 50})
+
+	cache.AddIndex("user", func(value session) string {
+		return value.userID
+	})
+
+	var wg sync.WaitGroup
+
+	for i := range 4 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := range 500 {
+				token := fmt.Sprintf("%d-%d", i, j%100)
+				cache.Set(token, session{token: token, userID: fmt.Sprintf("user-%d", j%3)})
+
+				if j%7 == 0 {
+					cache.Delete(token)
+				}
+
+				cache.GetByIndex("user", "user-0")
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// every indexed key must reference a live item with the matching attribute
+	cache.mut.RLock()
+	defer cache.mut.RUnlock()
+
+	indexed := 0
+
+	for attr, keys := range cache.indexes["user"].keys {
+		for key := range keys {
+			e, ok := cache.data[key]
+			if !ok || e.item.Data.userID != attr {
+				t.Errorf("index references %q for %q, which does not match the cache", key, attr)
+			}
+
+			indexed++
+		}
+	}
+
+	if indexed != len(cache.data) {
+		t.Errorf("got %d indexed items, want %d", indexed, len(cache.data))
+	}
+}
@@ -0,0 +1,90 @@
+package mempot
+
+import (
+	"context"
+	"time"
+)
+
+// KeyFunc maps a key to the string which is used to store the Item, e.g. to normalize keys
+// or to use keys which are not comparable.
+type KeyFunc[K any] func(key K) string
+
+// KeyedCache is a Cache which maps every key with a KeyFunc before accessing its Items.
+// Keys which are mapped to the same string refer to the same Item.
+type KeyedCache[K any, T any] struct {
+	cache   *Cache[string, T]
+	keyFunc KeyFunc[K]
+}
+
+// NewCacheWithKeyFunc creates a new KeyedCache instance with K as key and T as data.
+// Every key is mapped with the KeyFunc before it is used to access the underlying Cache.
+// If the context is canceled, the cache will stop its cleanup goroutine.
+func NewCacheWithKeyFunc[K any, T any](ctx context.Context, cfg Config, keyFunc KeyFunc[K]) *KeyedCache[K, T] {
+	return &KeyedCache[K, T]{
+		cache:   NewCache[string, T](ctx, cfg),
+		keyFunc: keyFunc,
+	}
+}
+
+// Set will add an Item to the KeyedCache with the default time-to-live.
+func (k *KeyedCache[K, T]) Set(key K, value T) {
+	k.cache.Set(k.keyFunc(key), value)
+}
+
+// SetWithTTL will add an Item to the KeyedCache with the given time-to-live.
+func (k *KeyedCache[K, T]) SetWithTTL(key K, data T, ttl time.Duration) {
+	k.cache.SetWithTTL(k.keyFunc(key), data, ttl)
+}
+
+// Get returns an Item and true if the Item was found in the KeyedCache and has not been expired.
+// An empty Item and false is returned when the Item was not found or has been expired.
+func (k *KeyedCache[K, T]) Get(key K) (Item[T], bool) {
+	return k.cache.Get(k.keyFunc(key))
+}
+
+// Exists returns true if the Item was found in the KeyedCache and has not been expired.
+func (k *KeyedCache[K, T]) Exists(key K) bool {
+	return k.cache.Exists(k.keyFunc(key))
+}
+
+// Remember tries to get the Item from the KeyedCache, if the Item is not found or expired query is called
+// with the original key to retrieve the data from source and put it into the KeyedCache.
+func (k *KeyedCache[K, T]) Remember(key K, query func(key K) (T, error)) (Item[T], error) {
+	return k.RememberWithTTL(key, query, k.cache.DefaultTTL())
+}
+
+// RememberWithTTL tries to get the Item from the KeyedCache, if the Item is not found or expired query is called
+// with the original key to retrieve the data from source and put it into the KeyedCache with the given time-to-live.
+func (k *KeyedCache[K, T]) RememberWithTTL(key K, query func(key K) (T, error), ttl time.Duration) (Item[T], error) {
+	return k.cache.RememberWithTTL(k.keyFunc(key), func(string) (T, error) {
+		return query(key)
+	}, ttl)
+}
+
+// RememberContext works like Remember, but passes the context to query.
+func (k *KeyedCache[K, T]) RememberContext(ctx context.Context, key K, query func(ctx context.Context, key K) (T, error)) (Item[T], error) {
+	return k.cache.RememberContext(ctx, k.keyFunc(key), func(ctx context.Context, _ string) (T, error) {
+		return query(ctx, key)
+	})
+}
+
+// Delete removes an Item from the KeyedCache.
+func (k *KeyedCache[K, T]) Delete(key K) {
+	k.cache.Delete(k.keyFunc(key))
+}
+
+// Len returns the number of Items in the KeyedCache.
+// Expired Items which have not been removed by a cleanup yet are counted as well.
+func (k *KeyedCache[K, T]) Len() int {
+	return k.cache.Len()
+}
+
+// Stats returns the statistics of the KeyedCache.
+func (k *KeyedCache[K, T]) Stats() Stats {
+	return k.cache.Stats()
+}
+
+// Reset removes all Items from the KeyedCache.
+func (k *KeyedCache[K, T]) Reset() {
+	k.cache.Reset()
+}
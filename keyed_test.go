@@ -0,0 +1,90 @@
+package mempot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupKeyedCache() (*KeyedCache[string, string], context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cache := NewCacheWithKeyFunc[string, string](ctx, Config{DefaultTTL: time.Minute}, strings.ToLower)
+
+	return cache, cancel
+}
+
+func TestKeyedCacheSetGet(t *testing.T) {
+	cache, cancel := setupKeyedCache()
+	defer cancel()
+
+	cache.Set("FOO", data)
+
+	item, ok := cache.Get("foo")
+	if !ok {
+		t.Fatalf("item not found")
+	}
+	if item.Data != data {
+		t.Errorf("got %s, want %s", item.Data, data)
+	}
+
+	if !cache.Exists("Foo") {
+		t.Errorf("item not found")
+	}
+
+	if cache.Len() != 1 {
+		t.Errorf("got %d items, want %d", cache.Len(), 1)
+	}
+
+	cache.Delete("fOO")
+
+	if _, ok := cache.Get("FOO"); ok {
+		t.Errorf("item found after delete")
+	}
+}
+
+func TestKeyedCacheRemember(t *testing.T) {
+	cache, cancel := setupKeyedCache()
+	defer cancel()
+
+	var keys []string
+	query := func(key string) (string, error) {
+		keys = append(keys, key)
+		return data, nil
+	}
+
+	for _, k := range []string{"Foo", "FOO", "foo"} {
+		item, err := cache.Remember(k, query)
+		if err != nil {
+			t.Fatalf("failed to remember item: %s", err)
+		}
+		if item.Data != data {
+			t.Errorf("got %s, want %s", item.Data, data)
+		}
+	}
+
+	if len(keys) != 1 || keys[0] != "Foo" {
+		t.Errorf("got queried keys %v, want %v", keys, []string{"Foo"})
+	}
+}
+
+func TestKeyedCacheStructKey(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type query struct {
+		Table   string
+		Columns []string
+	}
+
+	cache := NewCacheWithKeyFunc[query, string](ctx, Config{DefaultTTL: time.Minute}, func(q query) string {
+		return q.Table + ":" + strings.Join(q.Columns, ",")
+	})
+
+	cache.Set(query{Table: "users", Columns: []string{"id", "name"}}, data)
+
+	if !cache.Exists(query{Table: "users", Columns: []string{"id", "name"}}) {
+		t.Errorf("item not found")
+	}
+}
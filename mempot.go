@@ -1,10 +1,78 @@
 package mempot
 
 import (
+	"container/list"
 	"context"
+	"errors"
 	"fmt"
+	"iter"
+	"maps"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unsafe"
+)
+
+// ErrNotFound is returned by Cache.GetErr if the Item was not found or has been expired.
+// It can also be returned by a QueryFunc to signal that the requested data does not exist at the source,
+// RememberWithNegativeTTL caches this negative result.
+var ErrNotFound = errors.New("not found")
+
+// ErrCachedNotFound is returned by the Remember methods if a negative result has been cached
+// by RememberWithNegativeTTL and QueryFunc was not called. It wraps ErrNotFound.
+var ErrCachedNotFound = fmt.Errorf("cached: %w", ErrNotFound)
+
+// ErrQueryTimeout is returned by the Remember methods if QueryFunc did not return within the QueryTimeout.
+var ErrQueryTimeout = errors.New("query timed out")
+
+// ErrLockTimeout is returned by Cache.TryGet if the lock could not be acquired within the timeout.
+var ErrLockTimeout = errors.New("lock timed out")
+
+// errAbandoned is the cause of the context passed to a call once all of its waiters have stopped waiting.
+var errAbandoned = errors.New("query abandoned")
+
+// NoExpiration is returned by Cache.GetTTL for Items which will not expire.
+const NoExpiration time.Duration = -1
+
+// ZeroValuePolicy decides how the Remember methods handle a QueryFunc which returns the zero value of T
+// without an error, e.g. an empty string or a nil pointer for a result which is empty by accident.
+type ZeroValuePolicy int
+
+const (
+	// CacheZeroValues caches zero values like any other data.
+	CacheZeroValues ZeroValuePolicy = iota
+
+	// SkipZeroValues returns zero values to the caller without caching them,
+	// so QueryFunc is called again on the next Remember.
+	SkipZeroValues
+
+	// ShortTTLZeroValues caches zero values with the ZeroValueTTL instead of the time-to-live of the Remember method.
+	ShortTTLZeroValues
+)
+
+// lockPollInterval is the time Cache.TryGet waits between attempts to acquire the lock.
+const lockPollInterval = time.Microsecond * 50
+
+// minCleanupDelay is the minimum time between two cleanups of the cleanup goroutine which are not started
+// by the Ticker, so all Items expiring within this window are removed by a single cleanup.
+const minCleanupDelay = time.Millisecond * 50
+
+// EvictionPolicy decides which Item is evicted if MaxItems or MaxBytes is exceeded.
+type EvictionPolicy int
+
+const (
+	// LRU evicts the least recently used Item.
+	LRU EvictionPolicy = iota
+
+	// LFU evicts the Item which has been returned by Cache.Get the least number of times,
+	// ties are broken by evicting the least recently used Item.
+	// The most recently used Item is not evicted unless it is the only Item, so a newly added Item
+	// is not evicted immediately. Finding the Item to evict requires a scan of all Items.
+	LFU
 )
 
 // DefaultConfig contains all default values for a Cache.
@@ -16,169 +84,2607 @@ var DefaultConfig = Config{
 // Config allows to alter the configuration of a Cache.
 type Config struct {
 	// DefaultTTL is used by Cache.Set for the Item.TTL.
-	// If set to 0, the Item will not expire.
+	// It can be changed later by Cache.SetDefaultTTL.
 	//
 	// Default: 15m
 	DefaultTTL time.Duration
 
-	// CleanupInterval is used for the Ticker in the cleanup goroutine.
+	// CleanupInterval is used for the Ticker in the cleanup goroutine. Between the ticks, the cleanup goroutine
+	// also wakes up when the Item which expires next can be removed, but at most once every 50ms, so Items
+	// expiring shortly after each other are removed together.
 	// If set to 0, no cleanup goroutine will be created.
 	//
 	// Default: 5m
 	CleanupInterval time.Duration
+
+	// CleanupBatchSize is the maximum number of expired Items a cleanup removes while holding the write lock.
+	// If exceeded, the lock is released and acquired again, so other operations are not blocked
+	// until a large number of expired Items has been removed. All expired Items are still removed by each cleanup.
+	// If set to 0, a cleanup holds the lock until all expired Items have been removed.
+	//
+	// Default: 0
+	CleanupBatchSize int
+
+	// MaxItems is the maximum number of Items the Cache holds.
+	// If exceeded, an Item will be evicted according to the EvictionPolicy.
+	// If set to 0, the number of Items is not limited. It can be changed at runtime with Cache.Resize.
+	//
+	// Default: 0
+	MaxItems int
+
+	// InitialCapacity is the number of Items the Cache allocates space for on creation and on Reset,
+	// which avoids growing the internal map while a large number of Items is added.
+	// If set to 0, the internal map starts small and grows on demand.
+	//
+	// Default: 0
+	InitialCapacity int
+
+	// MaxBytes is the maximum estimated size in bytes of all Items the Cache holds.
+	// If exceeded, Items will be evicted according to the EvictionPolicy until the size is within the limit again.
+	// The size of an Item is estimated by the function set with Cache.SetSizer, which is required
+	// for MaxBytes to have any effect.
+	// If set to 0, the size of the Cache is not limited.
+	//
+	// Default: 0
+	MaxBytes int64
+
+	// SlidingExpiration resets the time-to-live of an Item to its original duration every time
+	// it is returned by Cache.Get. Frequently accessed Items will therefore never expire.
+	// Enabling SlidingExpiration requires Cache.Get to acquire the write lock, which reduces
+	// the throughput of concurrent reads.
+	//
+	// Default: false
+	SlidingExpiration bool
+
+	// EvictionPolicy decides which Item is evicted if MaxItems or MaxBytes is exceeded.
+	//
+	// Default: LRU
+	EvictionPolicy EvictionPolicy
+
+	// TTLJitter randomly changes the time-to-live of every Item by up to plus or minus the given duration
+	// to spread out the expiration of Items which have been added at the same time.
+	// It should be considerably smaller than the time-to-live of the Items.
+	// If set to 0, the time-to-live is not changed.
+	//
+	// Default: 0
+	TTLJitter time.Duration
+
+	// JitterSource is the source of randomness used for TTLJitter.
+	// If set to nil, a randomly seeded source is used.
+	//
+	// Default: nil
+	JitterSource rand.Source
+
+	// QueryTimeout is the maximum duration the Remember methods wait for QueryFunc to return.
+	// If exceeded, ErrQueryTimeout is returned and the result of QueryFunc is discarded once it returns.
+	// The context passed to a QueryContextFunc is canceled when the timeout is exceeded.
+	// If set to 0, the Remember methods wait until QueryFunc returns.
+	//
+	// Default: 0
+	QueryTimeout time.Duration
+
+	// MaxConcurrentQueries is the maximum number of QueryFuncs the Remember methods run at once across all keys.
+	// Callers exceeding the limit wait until a query returns or their context is canceled.
+	// A query is not run anymore once all callers waiting for it have been canceled.
+	// If set to 0, the number of concurrent queries is not limited.
+	//
+	// Default: 0
+	MaxConcurrentQueries int
+
+	// Shards is the number of shards used by NewShardedCache, each holding its own Cache.
+	// It is rounded up to the next power of two. NewCache ignores this field.
+	//
+	// Default: 1
+	Shards int
+
+	// GracePeriod is the duration expired Items are kept in the Cache before they are removed
+	// by a cleanup. Expired Items are never returned by Get, but can be served as stale data,
+	// e.g. with StaleWhileRevalidate.
+	// If set to 0, expired Items are removed by the next cleanup.
+	//
+	// Default: 0
+	GracePeriod time.Duration
+
+	// StaleWhileRevalidate enables the Remember methods to return expired Items which are still within
+	// the GracePeriod immediately, while QueryFunc is called in the background to refresh the Item.
+	// Concurrent refreshes for the same key are deduplicated.
+	//
+	// Default: false
+	StaleWhileRevalidate bool
+
+	// RefreshAhead is the window before the expiration of an Item in which a cleanup calls the QueryFunc
+	// of the Remember method which stored the Item again in the background to refresh it, so frequently used Items
+	// do not expire. Items stored by Set are not refreshed. Concurrent refreshes for the same key are deduplicated.
+	// As refreshes are started by the cleanup, RefreshAhead should be larger than the CleanupInterval.
+	// If set to 0, Items are not refreshed ahead of their expiration.
+	//
+	// Default: 0
+	RefreshAhead time.Duration
+
+	// LazyExpiration enables Cache.Get to remove an expired Item it encounters immediately instead of leaving it
+	// to the next cleanup, which is useful if the CleanupInterval is long or the cleanup is disabled.
+	// Items within the GracePeriod are not removed. The removal requires Cache.Get to acquire the write lock
+	// if the Item was not found.
+	//
+	// Default: false
+	LazyExpiration bool
+
+	// TrackAccessTime enables Cache.Get to record the time an Item has been accessed in Item.LastAccessedAt.
+	// Enabling TrackAccessTime requires Cache.Get to acquire the write lock, which reduces
+	// the throughput of concurrent reads.
+	//
+	// Default: false
+	TrackAccessTime bool
+
+	// RenewOnRemember resets the time-to-live of an Item to its original duration every time it is returned
+	// by a Remember method from the Cache, so Items which are remembered frequently do not expire.
+	// Unlike SlidingExpiration, Cache.Get does not reset the time-to-live.
+	//
+	// Default: false
+	RenewOnRemember bool
+
+	// ZeroValuePolicy decides how the Remember methods handle a QueryFunc which returns the zero value of T
+	// without an error. Detecting the zero value requires reflection.
+	//
+	// Default: CacheZeroValues
+	ZeroValuePolicy ZeroValuePolicy
+
+	// ZeroValueTTL is the time-to-live of zero values cached with the ShortTTLZeroValues policy.
+	// If set to 0, zero values are not cached.
+	//
+	// Default: 0
+	ZeroValueTTL time.Duration
+
+	// EventHistorySize is the number of the most recent Events kept in memory, which are returned
+	// by Cache.RecentEvents. If exceeded, the oldest Event is overwritten.
+	// If set to 0, no Events are kept.
+	//
+	// Default: 0
+	EventHistorySize int
+
+	// Clock is used to determine the current time, e.g. for the expiration of Items.
+	// If set to nil, the system clock is used.
+	//
+	// Default: nil
+	Clock Clock
+
+	// Logger is used to log the number of Items removed by each cleanup, e.g. a *log.Logger.
+	// If set to nil, nothing is logged.
+	//
+	// Default: nil
+	Logger Logger
+}
+
+// Clock provides the current time to a Cache.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// Logger logs messages of a Cache.
+type Logger interface {
+	// Printf logs a message, the arguments are handled in the manner of fmt.Printf.
+	Printf(format string, args ...any)
+}
+
+// systemClock is a Clock which uses time.Now.
+type systemClock struct{}
+
+// Now returns the current local time.
+func (systemClock) Now() time.Time {
+	return time.Now()
 }
 
 // Cache holds the data you want to cache in memory.
 type Cache[K comparable, T any] struct {
 	mut  sync.RWMutex
-	data map[K]Item[T]
+	data map[K]*entry[K, T]
+	lru  *list.List
+
+	// expiries holds all entries which expire ordered by their expiration time,
+	// so a cleanup does not have to scan all Items.
+	expiries expiryHeap[K, T]
 
 	ctx context.Context
 	cfg Config
+
+	// closed is closed by Close to stop the cleanup goroutine.
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	// cleanupMut guards cleanupDone, which is replaced whenever SetCleanupInterval starts a new cleanup goroutine.
+	cleanupMut sync.Mutex
+
+	// cleanupDone is closed when the cleanup goroutine has stopped.
+	cleanupDone chan struct{}
+
+	// intervals passes a new CleanupInterval to the running cleanup goroutine.
+	intervals chan time.Duration
+
+	// wake notifies the cleanup goroutine that the Item which expires next has changed.
+	wake chan struct{}
+
+	// scanMut is held by the cleanup goroutine while it removes expired Items, but not while it calls the
+	// callbacks, so PauseCleanup can wait for a running cleanup to finish even if it is called from a callback.
+	scanMut sync.Mutex
+	paused  atomic.Bool
+
+	// negatives holds the expiration of cached negative results by key.
+	negatives map[K]int64
+
+	// writes holds the writes of AsyncSet until they are applied by the goroutine started on the first call.
+	// asyncStopped is set once the goroutine has stopped and asyncDone is closed afterwards.
+	// writes and asyncDone are allocated by asyncOnce, so a Cache which never uses AsyncSet does not pay for the buffer.
+	writes       chan asyncWrite[K, T]
+	asyncOnce    sync.Once
+	asyncMut     sync.RWMutex
+	asyncStopped bool
+	asyncDone    chan struct{}
+
+	callsMut sync.Mutex
+	calls    map[K]*call[T]
+
+	// indexes holds the secondary indexes by name, see AddIndex.
+	indexes map[string]*secondaryIndex[K, T]
+
+	// queryLimit is a semaphore limiting the number of concurrent queries to MaxConcurrentQueries.
+	queryLimit chan struct{}
+
+	// subscribers receive an Event for every change, see Subscribe.
+	subscribers map[chan Event[K]]struct{}
+
+	// history is a ring buffer of the last EventHistorySize Events, historyNext is the position
+	// of the next Event and historyFull is true once the buffer has been filled.
+	history     []Event[K]
+	historyNext int
+	historyFull bool
+
+	onEvict   func(key K, value T)
+	onExpire  func(key K, value T)
+	onCleanup func(scanned, evicted int)
+	sizer     func(value T) int64
+	copier    func(value T) T
+	bytes     int64
+
+	marshal   func(value T) ([]byte, error)
+	unmarshal func(data []byte) (T, error)
+
+	randMut sync.Mutex
+	rand    *rand.Rand
+
+	// defaultTTL is the current default time-to-live, which can be changed by SetDefaultTTL.
+	defaultTTL atomic.Int64
+
+	// maxItems is the current MaxItems, which can be changed by Resize.
+	maxItems atomic.Int64
+
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	evictions     atomic.Uint64
+	queries       atomic.Uint64
+	queryDuration atomic.Int64
+}
+
+// Stats holds statistics about the usage of a Cache.
+type Stats struct {
+	// Hits is the number of Get calls which returned an Item.
+	Hits uint64
+
+	// Misses is the number of Get calls which did not return an Item.
+	Misses uint64
+
+	// Evictions is the number of expired Items which have been removed and of Items evicted because MaxItems
+	// or MaxBytes was exceeded.
+	Evictions uint64
+
+	// Queries is the number of QueryFunc calls made by the Remember methods.
+	Queries uint64
+
+	// QueryDuration is the total time spent in QueryFunc calls made by the Remember methods.
+	QueryDuration time.Duration
+}
+
+// AverageQueryDuration returns the mean time spent in a QueryFunc call made by the Remember methods.
+// Cache hits do not contribute to it. If no query has been made, 0 is returned.
+func (s Stats) AverageQueryDuration() time.Duration {
+	if s.Queries == 0 {
+		return 0
+	}
+
+	return s.QueryDuration / time.Duration(s.Queries)
+}
+
+// entry is the internal representation of an Item in the Cache.
+type entry[K comparable, T any] struct {
+	key  K
+	item Item[T]
+
+	// elem is the position of the entry in the least recently used list.
+	elem *list.Element
+
+	// ttl is the time-to-live the Item has been stored with.
+	ttl time.Duration
+
+	// size is the estimated size of the Item in bytes.
+	size int64
+
+	// refresh queries the Item again if RefreshAhead is enabled or is nil if the Item has not been stored
+	// by a Remember method.
+	refresh func(wait context.Context) (Item[T], bool, error)
+
+	// accesses is the number of times the Item has been returned by Cache.Get, used by the LFU EvictionPolicy.
+	accesses uint64
+
+	// index is the position of the entry in the expiry heap or -1 if the Item does not expire.
+	index int
+}
+
+// eviction is an Item which has been evicted from the Cache and has to be passed to the OnEvict callback.
+type eviction[K comparable, T any] struct {
+	key   K
+	value T
 }
 
-// Item is a unit of typed data which can be cached and has an expiration as Unix time in milliseconds.
+// Item is a unit of typed data which can be cached and has an expiration as Unix time in nanoseconds.
 type Item[T any] struct {
 	// Data holds the assigned data of the Item.
 	Data T
 
-	// TTL is the expiration time as Unix time in milliseconds.
+	// TTL is the expiration time as Unix time in nanoseconds, so even very short time-to-lives are exact.
 	// If set to 0, the Item will not expire.
 	TTL int64
+
+	// CreatedAt is the time the Item has been set as Unix time in nanoseconds.
+	// Modifying the data with Update or CompareAndSwap does not change it.
+	CreatedAt int64
+
+	// LastAccessedAt is the time the Item has last been returned by Cache.Get as Unix time in nanoseconds.
+	// It is only tracked if TrackAccessTime is enabled and 0 if the Item has not been accessed yet.
+	LastAccessedAt int64
+
+	// Version is an optional version or ETag of the data, e.g. to detect if the data in a shared backing store
+	// has changed. It is set by Cache.SetWithVersion and checked by Cache.GetIfVersion.
+	Version string
 }
 
 // Expired returns true if the data of the Item has expired.
+// The system clock is used to determine the current time.
 func (i *Item[T]) Expired() bool {
+	return i.expiredAt(time.Now())
+}
+
+func (i *Item[T]) expiredAt(now time.Time) bool {
 	if i.TTL == 0 {
 		return false
 	}
 
-	return time.Now().UnixMilli() > i.TTL
+	return now.UnixNano() > i.TTL
 }
 
-func newItem[T any](data T, ttl time.Duration) Item[T] {
-	if ttl == 0 {
-		return Item[T]{Data: data, TTL: 0}
-	}
+// ItemInput is the data and time-to-live of an Item which is added by Cache.SetBatch.
+type ItemInput[T any] struct {
+	// Value holds the data of the Item.
+	Value T
 
-	return Item[T]{Data: data, TTL: time.Now().Add(ttl).UnixMilli()}
+	// TTL is the time-to-live of the Item. If set to 0, the Item will not expire.
+	TTL time.Duration
 }
 
 // NewCache create a new Cache instance with K as key and T as data.
-// If the context is canceled, the Cache will stop the cleanup goroutine.
+// If the context is canceled, the Cache will stop the cleanup goroutine. If it has already been canceled,
+// no cleanup goroutine is started. In both cases the Cache can still be used like after Close,
+// so expired Items are only removed by calling Cleanup.
 func NewCache[K comparable, T any](ctx context.Context, cfg Config) *Cache[K, T] {
 	c := &Cache[K, T]{
-		data: make(map[K]Item[T]),
-		ctx:  ctx,
-		cfg:  DefaultConfig,
+		data:      make(map[K]*entry[K, T], max(cfg.InitialCapacity, 0)),
+		lru:       list.New(),
+		ctx:       ctx,
+		cfg:       DefaultConfig,
+		negatives: make(map[K]int64),
+		calls:     make(map[K]*call[T]),
+
+		closed:      make(chan struct{}),
+		cleanupDone: make(chan struct{}),
+		intervals:   make(chan time.Duration),
+		wake:        make(chan struct{}, 1),
 	}
 
 	if cfg.DefaultTTL > 0 {
 		c.cfg.DefaultTTL = cfg.DefaultTTL
 	}
 
+	c.defaultTTL.Store(int64(c.cfg.DefaultTTL))
+
 	if cfg.CleanupInterval > 0 {
 		c.cfg.CleanupInterval = cfg.CleanupInterval
 	}
 
-	if c.cfg.CleanupInterval > 0 {
-		go c.cleanup()
+	if cfg.CleanupBatchSize > 0 {
+		c.cfg.CleanupBatchSize = cfg.CleanupBatchSize
+	}
+
+	if cfg.MaxItems > 0 {
+		c.cfg.MaxItems = cfg.MaxItems
+	}
+
+	c.maxItems.Store(int64(c.cfg.MaxItems))
+
+	if cfg.InitialCapacity > 0 {
+		c.cfg.InitialCapacity = cfg.InitialCapacity
+	}
+
+	if cfg.MaxBytes > 0 {
+		c.cfg.MaxBytes = cfg.MaxBytes
+	}
+
+	c.cfg.SlidingExpiration = cfg.SlidingExpiration
+	c.cfg.EvictionPolicy = cfg.EvictionPolicy
+
+	if cfg.GracePeriod > 0 {
+		c.cfg.GracePeriod = cfg.GracePeriod
+	}
+
+	c.cfg.StaleWhileRevalidate = cfg.StaleWhileRevalidate
+
+	if cfg.RefreshAhead > 0 {
+		c.cfg.RefreshAhead = cfg.RefreshAhead
+	}
+
+	c.cfg.LazyExpiration = cfg.LazyExpiration
+	c.cfg.TrackAccessTime = cfg.TrackAccessTime
+	c.cfg.RenewOnRemember = cfg.RenewOnRemember
+	c.cfg.ZeroValuePolicy = cfg.ZeroValuePolicy
+
+	if cfg.ZeroValueTTL > 0 {
+		c.cfg.ZeroValueTTL = cfg.ZeroValueTTL
+	}
+
+	if cfg.EventHistorySize > 0 {
+		c.cfg.EventHistorySize = cfg.EventHistorySize
+		c.history = make([]Event[K], cfg.EventHistorySize)
+	}
+
+	if cfg.QueryTimeout > 0 {
+		c.cfg.QueryTimeout = cfg.QueryTimeout
+	}
+
+	if cfg.MaxConcurrentQueries > 0 {
+		c.cfg.MaxConcurrentQueries = cfg.MaxConcurrentQueries
+		c.queryLimit = make(chan struct{}, cfg.MaxConcurrentQueries)
+	}
+
+	if cfg.TTLJitter > 0 {
+		c.cfg.TTLJitter = cfg.TTLJitter
+		c.cfg.JitterSource = cfg.JitterSource
+
+		if c.cfg.JitterSource == nil {
+			c.cfg.JitterSource = rand.NewPCG(rand.Uint64(), rand.Uint64())
+		}
+
+		c.rand = rand.New(c.cfg.JitterSource)
+	}
+
+	c.cfg.Clock = systemClock{}
+	if cfg.Clock != nil {
+		c.cfg.Clock = cfg.Clock
+	}
+
+	c.cfg.Logger = cfg.Logger
+
+	if c.cfg.CleanupInterval > 0 && ctx.Err() == nil {
+		go c.runCleanup(c.cfg.CleanupInterval, c.cleanupDone)
+	} else {
+		close(c.cleanupDone)
 	}
 
 	return c
 }
 
+func (c *Cache[K, T]) newItem(data T, ttl time.Duration) Item[T] {
+	return Item[T]{Data: data, TTL: c.expiration(ttl), CreatedAt: c.now().UnixNano()}
+}
+
+// expiration returns the expiration time as Unix time in nanoseconds for the given time-to-live
+// including TTLJitter.
+func (c *Cache[K, T]) expiration(ttl time.Duration) int64 {
+	if ttl == 0 {
+		return 0
+	}
+
+	return c.now().Add(c.jitter(ttl)).UnixNano()
+}
+
+// jitter randomly changes the time-to-live by up to plus or minus TTLJitter.
+// The result is always positive, so the Item does not become non-expiring.
+func (c *Cache[K, T]) jitter(ttl time.Duration) time.Duration {
+	if c.cfg.TTLJitter <= 0 {
+		return ttl
+	}
+
+	c.randMut.Lock()
+	offset := time.Duration(c.rand.Int64N(int64(c.cfg.TTLJitter)*2+1)) - c.cfg.TTLJitter
+	c.randMut.Unlock()
+
+	return max(ttl+offset, 1)
+}
+
+// expired returns true if the data of the Item has expired according to the Clock of the Cache.
+func (c *Cache[K, T]) expired(item *Item[T]) bool {
+	return item.expiredAt(c.now())
+}
+
+// removable returns true if the Item has expired for longer than the GracePeriod and can be removed.
+func (c *Cache[K, T]) removable(item *Item[T]) bool {
+	return item.expiredAt(c.now().Add(-c.cfg.GracePeriod))
+}
+
+// expiredAt returns true if the expiration time as Unix time in nanoseconds has passed
+// according to the Clock of the Cache.
+func (c *Cache[K, T]) expiredAt(ttl int64) bool {
+	return ttl != 0 && c.now().UnixNano() > ttl
+}
+
+func (c *Cache[K, T]) now() time.Time {
+	return c.cfg.Clock.Now()
+}
+
 // Set will add an Item to the Cache with the default time-to-live.
 func (c *Cache[K, T]) Set(key K, value T) {
-	c.SetWithTTL(key, value, c.cfg.DefaultTTL)
+	c.SetWithTTL(key, value, c.DefaultTTL())
 }
 
 // SetWithTTL will add an Item to the Cache with the given time-to-live.
 func (c *Cache[K, T]) SetWithTTL(key K, data T, ttl time.Duration) {
+	c.store(key, data, ttl)
+}
+
+// store adds an Item to the Cache with the given time-to-live and returns the stored Item.
+func (c *Cache[K, T]) store(key K, data T, ttl time.Duration) Item[T] {
+	item := c.newItem(data, ttl)
+
 	c.mut.Lock()
-	c.data[key] = newItem(data, ttl)
+	evicted := c.set(key, item, ttl)
 	c.mut.Unlock()
+
+	c.notifyEvicted(evicted)
+
+	return item
 }
 
-// Get returns an Item and true if the Item was found in the Cache and has not been expired.
-// An empty Item and false is returned when the Item was not found or has been expired.
-func (c *Cache[K, T]) Get(key K) (Item[T], bool) {
-	c.mut.RLock()
-	item, ok := c.data[key]
-	c.mut.RUnlock()
+// SetWithVersion will add an Item with the given version to the Cache with the default time-to-live.
+func (c *Cache[K, T]) SetWithVersion(key K, data T, version string) {
+	c.SetWithTTLAndVersion(key, data, c.DefaultTTL(), version)
+}
 
-	if item.Expired() {
-		return Item[T]{}, false
-	}
+// SetWithTTLAndVersion will add an Item with the given version to the Cache with the given time-to-live.
+func (c *Cache[K, T]) SetWithTTLAndVersion(key K, data T, ttl time.Duration, version string) {
+	item := c.newItem(data, ttl)
+	item.Version = version
+
+	c.mut.Lock()
+	evicted := c.set(key, item, ttl)
+	c.mut.Unlock()
 
-	return item, ok
+	c.notifyEvicted(evicted)
 }
 
-// QueryFunc is a function to retrieve data which will be put into the Cache.
-type QueryFunc[K comparable, T any] func(key K) (T, error)
+// SetWithDeadline will add an Item to the Cache which expires at the given deadline.
+// If the deadline is in the past, the Item is expired immediately.
+func (c *Cache[K, T]) SetWithDeadline(key K, data T, deadline time.Time) {
+	c.mut.Lock()
+	now := c.now()
+	evicted := c.set(key, Item[T]{Data: data, TTL: deadline.UnixNano(), CreatedAt: now.UnixNano()}, deadline.Sub(now))
+	c.mut.Unlock()
 
-// Remember tries to get the Item from the Cache, if the Item is not found or expired QueryFunc is called
-// to retrieve the data from source and put it into the Cache.
-func (c *Cache[K, T]) Remember(key K, query QueryFunc[K, T]) (Item[T], error) {
-	return c.RememberWithTTL(key, query, c.cfg.DefaultTTL)
+	c.notifyEvicted(evicted)
 }
 
-// RememberWithTTL tries to get the Item from the Cache, if the Item is not found or expired QueryFunc is called
-// to retrieve the data from source and put it into the Cache with the given time-to-live.
-func (c *Cache[K, T]) RememberWithTTL(key K, query QueryFunc[K, T], ttl time.Duration) (Item[T], error) {
-	item, ok := c.Get(key)
+// SetBatch adds multiple Items with their own time-to-live to the Cache while acquiring the lock only once.
+func (c *Cache[K, T]) SetBatch(items map[K]ItemInput[T]) {
+	var evicted []eviction[K, T]
+
+	c.mut.Lock()
+	for key, input := range items {
+		evicted = append(evicted, c.set(key, c.newItem(input.Value, input.TTL), input.TTL)...)
+	}
+	c.mut.Unlock()
+
+	c.notifyEvicted(evicted)
+}
+
+// set stores the Item and evicts Items according to the EvictionPolicy if MaxItems or MaxBytes is exceeded.
+// The evicted Items are returned and have to be passed to notifyEvicted after releasing the lock.
+// The caller must hold the write lock.
+func (c *Cache[K, T]) set(key K, item Item[T], ttl time.Duration) []eviction[K, T] {
+	item = c.copied(item)
+	size := c.sizeOf(item.Data)
+
+	delete(c.negatives, key)
+
+	e, ok := c.data[key]
 	if ok {
-		return item, nil
+		c.bytes -= e.size
+		e.item = item
+		e.ttl = ttl
+		e.size = size
+		// the Item is not refreshed unless fetch registers its query again after storing it
+		e.refresh = nil
+		c.lru.MoveToFront(e.elem)
+	} else {
+		e = &entry[K, T]{key: key, item: item, elem: c.lru.PushFront(key), ttl: ttl, size: size, index: -1}
+		c.data[key] = e
 	}
 
-	data, err := query(key)
-	if err != nil {
-		return Item[T]{}, fmt.Errorf("failed to query data: %w", err)
+	c.bytes += size
+	c.indexAdd(key, item.Data)
+	c.updateExpiry(e)
+	c.publish(EventSet, key)
+
+	return c.evict()
+}
+
+// evict removes Items according to the EvictionPolicy until neither MaxItems nor MaxBytes is exceeded.
+// The evicted Items are returned and have to be passed to notifyEvicted after releasing the lock.
+// The caller must hold the write lock.
+func (c *Cache[K, T]) evict() []eviction[K, T] {
+	var evicted []eviction[K, T]
+
+	for c.exceeded() {
+		k := c.victim()
+		evicted = append(evicted, eviction[K, T]{key: k, value: c.data[k].item.Data})
+		c.remove(k)
+		c.publish(EventEvict, k)
 	}
 
-	c.SetWithTTL(key, data, ttl)
+	c.evictions.Add(uint64(len(evicted)))
 
-	return newItem(data, ttl), nil
+	return evicted
 }
 
-// Delete removes an Item from the Cache.
-func (c *Cache[K, T]) Delete(key K) {
-	c.mut.Lock()
-	delete(c.data, key)
-	c.mut.Unlock()
+// victim returns the key of the Item to be evicted next according to the EvictionPolicy.
+// The caller must hold at least the read lock and the Cache must not be empty.
+func (c *Cache[K, T]) victim() K {
+	victim := c.lru.Back()
+
+	if c.cfg.EvictionPolicy == LFU {
+		// the most recently used Item is skipped, so a newly added Item is not evicted immediately,
+		// ties are broken by evicting the least recently used Item
+		for elem := victim.Prev(); elem != nil && elem != c.lru.Front(); elem = elem.Prev() {
+			if c.data[elem.Value.(K)].accesses < c.data[victim.Value.(K)].accesses {
+				victim = elem
+			}
+		}
+	}
+
+	return victim.Value.(K)
 }
 
-// Reset removes all Items from the Cache.
-func (c *Cache[K, T]) Reset() {
-	c.mut.Lock()
-	c.data = make(map[K]Item[T])
-	c.mut.Unlock()
+// exceeded returns true if the Cache holds more Items than MaxItems or more bytes than MaxBytes.
+// The caller must hold at least the read lock.
+func (c *Cache[K, T]) exceeded() bool {
+	if len(c.data) == 0 {
+		return false
+	}
+
+	maxItems := int(c.maxItems.Load())
+
+	return (maxItems > 0 && len(c.data) > maxItems) || (c.cfg.MaxBytes > 0 && c.bytes > c.cfg.MaxBytes)
 }
 
-func (c *Cache[K, T]) cleanup() {
-	ticker := time.NewTicker(c.cfg.CleanupInterval)
+// sizeOf returns the estimated size of the value in bytes or 0 if no sizer has been set.
+// The caller must hold at least the read lock.
+func (c *Cache[K, T]) sizeOf(value T) int64 {
+	if c.sizer == nil {
+		return 0
+	}
 
-	for {
-		select {
-		case <-c.ctx.Done():
-			ticker.Stop()
-			return
-		case <-ticker.C:
-			toBeDeleted := make([]K, 0)
+	return c.sizer(value)
+}
 
-			c.mut.RLock()
-			for key, item := range c.data {
-				if item.Expired() {
-					toBeDeleted = append(toBeDeleted, key)
-				}
-			}
-			c.mut.RUnlock()
+// notifyEvicted passes the evicted Items to the OnEvict callback.
+// The caller must not hold the lock.
+func (c *Cache[K, T]) notifyEvicted(evicted []eviction[K, T]) {
+	if len(evicted) == 0 {
+		return
+	}
 
-			c.mut.Lock()
-			for _, key := range toBeDeleted {
-				delete(c.data, key)
-			}
-			c.mut.Unlock()
-		}
+	c.mut.RLock()
+	fn := c.onEvict
+	c.mut.RUnlock()
+
+	c.notify("OnEvict", fn, evicted)
+}
+
+// notifyExpired passes the expired Items to the OnExpire and OnEvict callbacks.
+// The caller must not hold the lock.
+func (c *Cache[K, T]) notifyExpired(expired []eviction[K, T]) {
+	if len(expired) == 0 {
+		return
 	}
+
+	c.mut.RLock()
+	onExpire, onEvict := c.onExpire, c.onEvict
+	c.mut.RUnlock()
+
+	c.notify("OnExpire", onExpire, expired)
+	c.notify("OnEvict", onEvict, expired)
+}
+
+// notify calls the callback for every evicted Item if the callback is set.
+// A panic in the callback is recovered and logged, so it neither skips the remaining Items
+// nor stops the cleanup goroutine.
+func (c *Cache[K, T]) notify(name string, fn func(key K, value T), evicted []eviction[K, T]) {
+	if fn == nil {
+		return
+	}
+
+	for _, e := range evicted {
+		c.callback(name, func() {
+			fn(e.key, e.value)
+		})
+	}
+}
+
+// callback runs fn and recovers from a panic, which is logged with the Logger if set.
+func (c *Cache[K, T]) callback(name string, fn func()) {
+	defer func() {
+		if r := recover(); r != nil && c.cfg.Logger != nil {
+			c.cfg.Logger.Printf("mempot: recovered from panic in %s callback: %v", name, r)
+		}
+	}()
+
+	fn()
+}
+
+// remove deletes the Item and any cached negative result from the Cache
+// and returns true if an Item has been deleted.
+// The caller must hold the write lock.
+func (c *Cache[K, T]) remove(key K) bool {
+	delete(c.negatives, key)
+
+	e, ok := c.data[key]
+	if !ok {
+		return false
+	}
+
+	c.lru.Remove(e.elem)
+	c.removeExpiry(e)
+	c.indexRemove(key)
+	c.bytes -= e.size
+	delete(c.data, key)
+
+	return true
+}
+
+// Get returns an Item and true if the Item was found in the Cache and has not been expired.
+// An empty Item and false is returned when the Item was not found or has been expired.
+func (c *Cache[K, T]) Get(key K) (Item[T], bool) {
+	var (
+		item      Item[T]
+		ok        bool
+		removable bool
+	)
+
+	if c.touchOnGet() {
+		c.mut.Lock()
+		item, ok = c.lookup(key, true)
+		removable = !ok && c.lazyRemovable(key)
+		c.mut.Unlock()
+	} else {
+		c.mut.RLock()
+		item, ok = c.lookup(key, false)
+		removable = !ok && c.lazyRemovable(key)
+		c.mut.RUnlock()
+	}
+
+	return c.record(key, item, ok, removable)
+}
+
+// TryGet works like Get, but gives up if the lock of the Cache could not be acquired within the timeout
+// and returns ErrLockTimeout, e.g. to skip a heavily contended Cache in latency-sensitive paths.
+// The lock is polled until the timeout has passed, so TryGet does not queue behind waiting writers like Get.
+// Unlike Get, TryGet does not remove an expired Item if LazyExpiration is enabled, as it would have to wait
+// for the write lock. It is removed by the next Get or cleanup instead.
+func (c *Cache[K, T]) TryGet(key K, timeout time.Duration) (Item[T], bool, error) {
+	touch := c.touchOnGet()
+
+	tryLock, unlock := c.mut.TryRLock, c.mut.RUnlock
+	if touch {
+		tryLock, unlock = c.mut.TryLock, c.mut.Unlock
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for !tryLock() {
+		if !time.Now().Before(deadline) {
+			return Item[T]{}, false, ErrLockTimeout
+		}
+
+		time.Sleep(lockPollInterval)
+	}
+
+	item, ok := c.lookup(key, touch)
+	unlock()
+
+	if !ok {
+		c.misses.Add(1)
+		return Item[T]{}, false, nil
+	}
+
+	c.hits.Add(1)
+
+	return item, true, nil
+}
+
+// record updates the statistics for the result of a lookup by Get and removes the Item
+// if it has been found to be removable by lazyRemovable.
+func (c *Cache[K, T]) record(key K, item Item[T], ok, removable bool) (Item[T], bool) {
+	if !ok {
+		c.misses.Add(1)
+
+		if removable {
+			c.removeExpired(key)
+		}
+
+		return Item[T]{}, false
+	}
+
+	c.hits.Add(1)
+
+	return item, true
+}
+
+// LookupResult is the result of Cache.GetOrdered for a single key.
+type LookupResult[K comparable, T any] struct {
+	Key  K
+	Item Item[T]
+
+	// OK is true if the Item was found in the Cache and has not been expired.
+	OK bool
+}
+
+// GetOrdered works like Get for multiple keys while acquiring the lock only once and returns one result
+// per key in the order of the keys, so misses can be told apart by their position.
+func (c *Cache[K, T]) GetOrdered(keys []K) []LookupResult[K, T] {
+	results := make([]LookupResult[K, T], len(keys))
+
+	touch := c.touchOnGet()
+	if touch {
+		c.mut.Lock()
+	} else {
+		c.mut.RLock()
+	}
+
+	removable := make([]bool, len(keys))
+
+	for i, key := range keys {
+		item, ok := c.lookup(key, touch)
+		results[i] = LookupResult[K, T]{Key: key, Item: item, OK: ok}
+		removable[i] = !ok && c.lazyRemovable(key)
+	}
+
+	if touch {
+		c.mut.Unlock()
+	} else {
+		c.mut.RUnlock()
+	}
+
+	for i, r := range results {
+		results[i].Item, results[i].OK = c.record(r.Key, r.Item, r.OK, removable[i])
+	}
+
+	return results
+}
+
+// GetIfVersion returns the Item like Get, but only if it has been stored with the given version.
+// An Item with a different version is treated as not found, but is left in the Cache.
+func (c *Cache[K, T]) GetIfVersion(key K, version string) (Item[T], bool) {
+	item, ok := c.Get(key)
+	if !ok || item.Version != version {
+		return Item[T]{}, false
+	}
+
+	return item, true
+}
+
+// lazyRemovable returns true if LazyExpiration is enabled and an Item for the key exists which has been expired
+// for longer than the GracePeriod, so Get only acquires the write lock to remove it if there is something to remove.
+// The caller must hold at least the read lock.
+func (c *Cache[K, T]) lazyRemovable(key K) bool {
+	if !c.cfg.LazyExpiration {
+		return false
+	}
+
+	e, ok := c.data[key]
+
+	return ok && c.removable(&e.item)
+}
+
+// removeExpired removes the Item if it has been expired for longer than the GracePeriod.
+func (c *Cache[K, T]) removeExpired(key K) {
+	c.mut.Lock()
+
+	e, ok := c.data[key]
+	if !ok || !c.removable(&e.item) {
+		c.mut.Unlock()
+		return
+	}
+
+	c.remove(key)
+	c.publish(EventExpire, key)
+	c.mut.Unlock()
+
+	c.evictions.Add(1)
+	c.notifyExpired([]eviction[K, T]{{key: key, value: e.item.Data}})
+}
+
+// GetErr returns the data of an Item if the Item was found in the Cache and has not been expired.
+// ErrNotFound is returned when the Item was not found or has been expired.
+func (c *Cache[K, T]) GetErr(key K) (T, error) {
+	item, ok := c.Get(key)
+	if !ok {
+		var zero T
+		return zero, ErrNotFound
+	}
+
+	return item.Data, nil
+}
+
+// GetOrDefault returns the data of an Item if the Item was found in the Cache and has not been expired.
+// Otherwise the fallback is returned, which is not stored in the Cache.
+func (c *Cache[K, T]) GetOrDefault(key K, fallback T) T {
+	item, ok := c.Get(key)
+	if !ok {
+		return fallback
+	}
+
+	return item.Data
+}
+
+// GetWithExpiry returns the data of an Item, its absolute expiration time and true if the Item was found
+// in the Cache and has not been expired. The expiration time is the zero time.Time if the Item will not expire,
+// which can be checked with time.Time.IsZero.
+// The zero value of T, the zero time.Time and false is returned when the Item was not found or has been expired.
+func (c *Cache[K, T]) GetWithExpiry(key K) (T, time.Time, bool) {
+	item, ok := c.Get(key)
+	if !ok {
+		var zero T
+		return zero, time.Time{}, false
+	}
+
+	if item.TTL == 0 {
+		return item.Data, time.Time{}, true
+	}
+
+	return item.Data, time.Unix(0, item.TTL), true
+}
+
+// GetTTL returns the remaining time-to-live of an Item and true if the Item was found in the Cache
+// and has not been expired. NoExpiration and true is returned if the Item will not expire.
+// Zero and false is returned when the Item was not found or has been expired.
+func (c *Cache[K, T]) GetTTL(key K) (time.Duration, bool) {
+	c.mut.RLock()
+	item, ok := c.lookup(key, false)
+	c.mut.RUnlock()
+
+	if !ok {
+		return 0, false
+	}
+
+	if item.TTL == 0 {
+		return NoExpiration, true
+	}
+
+	return max(time.Unix(0, item.TTL).Sub(c.now()), 0), true
+}
+
+// Touch sets the time-to-live of an Item to the given duration without replacing its data.
+// False is returned if the Item was not found or has already been expired.
+func (c *Cache[K, T]) Touch(key K, ttl time.Duration) bool {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	e, ok := c.data[key]
+	if !ok || c.expired(&e.item) {
+		return false
+	}
+
+	e.item.TTL = c.expiration(ttl)
+	e.ttl = ttl
+	c.updateExpiry(e)
+	c.lru.MoveToFront(e.elem)
+
+	return true
+}
+
+// ExpireAt sets the expiration of an Item to the given time without replacing its data.
+// If the time is in the past, the Item is expired immediately.
+// False is returned if the Item was not found or has already been expired.
+func (c *Cache[K, T]) ExpireAt(key K, at time.Time) bool {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	e, ok := c.data[key]
+	if !ok || c.expired(&e.item) {
+		return false
+	}
+
+	e.item.TTL = at.UnixNano()
+	e.ttl = at.Sub(c.now())
+	c.updateExpiry(e)
+
+	return true
+}
+
+// Persist removes the expiration of an Item, so it will not expire until it is deleted.
+// False is returned if the Item was not found or has already been expired.
+func (c *Cache[K, T]) Persist(key K) bool {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	e, ok := c.data[key]
+	if !ok || c.expired(&e.item) {
+		return false
+	}
+
+	e.item.TTL = 0
+	e.ttl = 0
+	c.updateExpiry(e)
+
+	return true
+}
+
+// GetOrSet returns the existing Item and true if the Item was found in the Cache and has not been expired.
+// Otherwise, the value is added to the Cache with the default time-to-live and the new Item and false is returned.
+// The lookup and the insertion happen atomically.
+func (c *Cache[K, T]) GetOrSet(key K, value T) (Item[T], bool) {
+	c.mut.Lock()
+
+	if item, ok := c.lookup(key, true); ok {
+		c.mut.Unlock()
+		return item, true
+	}
+
+	ttl := c.DefaultTTL()
+	item := c.newItem(value, ttl)
+	evicted := c.set(key, item, ttl)
+	c.mut.Unlock()
+
+	c.notifyEvicted(evicted)
+
+	return item, false
+}
+
+// SetIfAbsent will add an Item to the Cache with the default time-to-live if no Item was found
+// for the key or the Item has been expired. True is returned if the Item was added,
+// false if an existing Item has been left unchanged. The lookup and the insertion happen atomically.
+func (c *Cache[K, T]) SetIfAbsent(key K, value T) bool {
+	return c.SetWithTTLIfAbsent(key, value, c.DefaultTTL())
+}
+
+// SetWithTTLIfAbsent will add an Item to the Cache with the given time-to-live if no Item was found
+// for the key or the Item has been expired. True is returned if the Item was added,
+// false if an existing Item has been left unchanged. The lookup and the insertion happen atomically,
+// so it can be used to acquire a lock which is released by Delete or by its expiration.
+func (c *Cache[K, T]) SetWithTTLIfAbsent(key K, value T, ttl time.Duration) bool {
+	c.mut.Lock()
+
+	if e, ok := c.data[key]; ok && !c.expired(&e.item) {
+		c.mut.Unlock()
+		return false
+	}
+
+	evicted := c.set(key, c.newItem(value, ttl), ttl)
+	c.mut.Unlock()
+
+	c.notifyEvicted(evicted)
+
+	return true
+}
+
+// Replace will update an Item in the Cache with the default time-to-live if an Item was found
+// for the key and has not been expired. True is returned if the Item was updated, false if no live Item exists.
+// The lookup and the update happen atomically.
+func (c *Cache[K, T]) Replace(key K, value T) bool {
+	c.mut.Lock()
+
+	if e, ok := c.data[key]; !ok || c.expired(&e.item) {
+		c.mut.Unlock()
+		return false
+	}
+
+	ttl := c.DefaultTTL()
+	evicted := c.set(key, c.newItem(value, ttl), ttl)
+	c.mut.Unlock()
+
+	c.notifyEvicted(evicted)
+
+	return true
+}
+
+// Update replaces the data of an Item with the result of fn, which is called with the current data,
+// if the Item was found in the Cache and has not been expired. The expiration of the Item is preserved.
+// True is returned if the Item was updated, false if no live Item exists.
+// The write lock is held while fn is called, so the read-modify-write happens atomically
+// and fn must not access the Cache.
+func (c *Cache[K, T]) Update(key K, fn func(old T) T) bool {
+	evicted, ok := c.update(key, fn)
+
+	c.notifyEvicted(evicted)
+
+	return ok
+}
+
+// update calls fn and stores its result while holding the write lock.
+// The lock is released in a defer, so a panic in fn which is recovered by the caller does not leave it held.
+func (c *Cache[K, T]) update(key K, fn func(old T) T) ([]eviction[K, T], bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	e, ok := c.data[key]
+	if !ok || c.expired(&e.item) {
+		return nil, false
+	}
+
+	item := e.item
+	item.Data = fn(item.Data)
+
+	return c.set(key, item, e.ttl), true
+}
+
+// touchOnGet returns true if Cache.Get has to modify the accessed Item and therefore requires the write lock.
+func (c *Cache[K, T]) touchOnGet() bool {
+	return c.maxItems.Load() > 0 || c.cfg.MaxBytes > 0 || c.cfg.SlidingExpiration || c.cfg.TrackAccessTime
+}
+
+// lookup returns the Item and true if the Item was found and has not been expired.
+// If touch is true, the Item is marked as recently used and its time-to-live is reset
+// if SlidingExpiration is enabled, which requires the write lock.
+// The caller must hold at least the read lock.
+func (c *Cache[K, T]) lookup(key K, touch bool) (Item[T], bool) {
+	e, ok := c.data[key]
+	if !ok || c.expired(&e.item) {
+		return Item[T]{}, false
+	}
+
+	if touch {
+		c.lru.MoveToFront(e.elem)
+		e.accesses++
+
+		if c.cfg.TrackAccessTime {
+			e.item.LastAccessedAt = c.now().UnixNano()
+		}
+
+		if c.cfg.SlidingExpiration {
+			e.item.TTL = c.expiration(e.ttl)
+			c.updateExpiry(e)
+		}
+	}
+
+	return c.copied(e.item), true
+}
+
+// GetStale works like Get, but also returns Items which have been expired and have not been removed
+// by a cleanup yet, e.g. to serve stale data while the source is unavailable. The first bool is true
+// if the Item was found, the second bool is true if the Item has been expired.
+// Expired Items are kept until the next cleanup after the GracePeriod has passed, so increasing the GracePeriod
+// increases the time stale Items are available. Returning a stale Item counts as a miss.
+func (c *Cache[K, T]) GetStale(key K) (Item[T], bool, bool) {
+	item, ok := c.Get(key)
+	if ok {
+		return item, true, false
+	}
+
+	c.mut.RLock()
+	e, ok := c.data[key]
+	if ok {
+		item = c.copied(e.item)
+	}
+	c.mut.RUnlock()
+
+	if !ok {
+		return Item[T]{}, false, false
+	}
+
+	return item, true, c.expired(&item)
+}
+
+// Peek returns an Item and true if the Item was found in the Cache and has not been expired.
+// Unlike Get, the Item is not marked as recently used, its time-to-live is not reset by SlidingExpiration
+// and the statistics of the Cache are not updated.
+func (c *Cache[K, T]) Peek(key K) (Item[T], bool) {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	return c.lookup(key, false)
+}
+
+// Exists returns true if the Item was found in the Cache and has not been expired.
+// Unlike Get, the Item is not copied and not marked as recently used.
+func (c *Cache[K, T]) Exists(key K) bool {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	e, ok := c.data[key]
+
+	return ok && !c.expired(&e.item)
+}
+
+// QueryFunc is a function to retrieve data which will be put into the Cache.
+type QueryFunc[K comparable, T any] func(key K) (T, error)
+
+// QueryContextFunc is a context-aware function to retrieve data which will be put into the Cache.
+type QueryContextFunc[K comparable, T any] func(ctx context.Context, key K) (T, error)
+
+// withContext returns a QueryContextFunc which ignores the context and calls the QueryFunc.
+func (q QueryFunc[K, T]) withContext() QueryContextFunc[K, T] {
+	return func(_ context.Context, key K) (T, error) {
+		return q(key)
+	}
+}
+
+// Remember tries to get the Item from the Cache, if the Item is not found or expired QueryFunc is called
+// to retrieve the data from source and put it into the Cache.
+// Concurrent calls for the same key are deduplicated, so QueryFunc is called only once and all callers
+// receive the same result. Calls for the same key are serialized, a call which overlaps with one that has
+// just stored the Item returns it instead of calling QueryFunc again. Calls for different keys run concurrently.
+func (c *Cache[K, T]) Remember(key K, query QueryFunc[K, T]) (Item[T], error) {
+	return c.RememberWithTTL(key, query, c.DefaultTTL())
+}
+
+// RememberWithTTL tries to get the Item from the Cache, if the Item is not found or expired QueryFunc is called
+// to retrieve the data from source and put it into the Cache with the given time-to-live.
+// Concurrent calls for the same key are deduplicated, so QueryFunc is called only once and all callers
+// receive the same result.
+func (c *Cache[K, T]) RememberWithTTL(key K, query QueryFunc[K, T], ttl time.Duration) (Item[T], error) {
+	item, _, err := c.rememberContext(context.Background(), key, query.withContext(), rememberOptions[T]{ttl: ttl})
+	return item, err
+}
+
+// RememberIf works like Remember, but the data returned by QueryFunc is only put into the Cache
+// if shouldCache returns true for it, e.g. to avoid caching empty results.
+// Otherwise the data is returned without being cached, so the next call queries it again.
+func (c *Cache[K, T]) RememberIf(key K, query QueryFunc[K, T], shouldCache func(value T) bool) (Item[T], error) {
+	opts := rememberOptions[T]{ttl: c.DefaultTTL(), shouldCache: shouldCache}
+	item, _, err := c.rememberContext(context.Background(), key, query.withContext(), opts)
+
+	return item, err
+}
+
+// RememberMany gets the Items for all keys from the Cache and calls query once with all keys whose Items
+// are not found or expired to retrieve their data from source in a single batch. The data returned by query
+// is put into the Cache with the default time-to-live and returned together with the cached Items.
+// Keys which are omitted by query are absent from the result. If query fails, no Items are returned.
+// Unlike Remember, concurrent calls for the same keys are not deduplicated.
+func (c *Cache[K, T]) RememberMany(keys []K, query func(missing []K) (map[K]T, error)) (map[K]Item[T], error) {
+	items := make(map[K]Item[T], len(keys))
+
+	var missing []K
+
+	for _, key := range keys {
+		item, ok := c.Get(key)
+		if !ok {
+			missing = append(missing, key)
+			continue
+		}
+
+		items[key] = item
+	}
+
+	if len(missing) == 0 {
+		return items, nil
+	}
+
+	start := time.Now()
+	results, err := query(missing)
+
+	c.queries.Add(1)
+	c.queryDuration.Add(int64(time.Since(start)))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query data: %w", err)
+	}
+
+	ttl := c.DefaultTTL()
+
+	for _, key := range missing {
+		data, ok := results[key]
+		if !ok {
+			continue
+		}
+
+		itemTTL, ok := c.zeroValueTTL(data, ttl)
+		if !ok {
+			items[key] = c.newItem(data, ttl)
+			continue
+		}
+
+		items[key] = c.store(key, data, itemTTL)
+	}
+
+	return items, nil
+}
+
+// RememberWithStatus works like Remember, but additionally returns true if the Item has been served
+// from the Cache and false if QueryFunc has been called to retrieve the data.
+func (c *Cache[K, T]) RememberWithStatus(key K, query QueryFunc[K, T]) (Item[T], bool, error) {
+	return c.rememberContext(context.Background(), key, query.withContext(), rememberOptions[T]{ttl: c.DefaultTTL()})
+}
+
+// RememberOrStale works like Remember, but if QueryFunc fails and the Item has been expired within the GracePeriod,
+// the stale Item is returned instead of the error, e.g. to keep serving data while the source is unavailable.
+// The bool is true if the returned Item is stale. The error is only returned if no stale Item exists,
+// which requires a GracePeriod to keep expired Items in the Cache.
+func (c *Cache[K, T]) RememberOrStale(key K, query QueryFunc[K, T]) (Item[T], bool, error) {
+	item, _, err := c.rememberContext(context.Background(), key, query.withContext(), rememberOptions[T]{ttl: c.DefaultTTL()})
+	if err == nil {
+		// StaleWhileRevalidate serves stale Items without an error
+		return item, c.expired(&item), nil
+	}
+
+	if stale, ok := c.stale(key); ok {
+		return stale, true, nil
+	}
+
+	return Item[T]{}, false, err
+}
+
+// RememberWithNegativeTTL works like RememberWithTTL, but if QueryFunc returns an error which wraps ErrNotFound,
+// the negative result is cached with negativeTTL. Until the negative result expires or the Item is set,
+// all Remember methods return ErrCachedNotFound without calling QueryFunc.
+func (c *Cache[K, T]) RememberWithNegativeTTL(key K, query QueryFunc[K, T], ttl, negativeTTL time.Duration) (Item[T], error) {
+	opts := rememberOptions[T]{ttl: ttl, negative: true, negativeTTL: negativeTTL}
+
+	item, _, err := c.rememberContext(context.Background(), key, query.withContext(), opts)
+
+	return item, err
+}
+
+// LoadOrStore tries to get the Item from the Cache, if the Item is not found or expired QueryFunc is called
+// to retrieve the data from source and put it into the Cache with the default time-to-live.
+// Unlike Remember, the write lock is held while QueryFunc is called, so no other operation can interleave
+// between the lookup and the insertion. This blocks all other operations on the Cache for the duration
+// of QueryFunc and serializes all misses, even for different keys, so it is only suitable for paths with
+// low concurrency, where Remember with its per-key deduplication is usually the better choice.
+// QueryFunc must not access the Cache.
+func (c *Cache[K, T]) LoadOrStore(key K, query QueryFunc[K, T]) (Item[T], error) {
+	item, evicted, err := c.loadOrStore(key, query)
+
+	c.notifyEvicted(evicted)
+
+	return item, err
+}
+
+// loadOrStore looks up the Item and calls QueryFunc while holding the write lock.
+// The lock is released in a defer, so a panic in QueryFunc which is recovered by the caller does not leave it held.
+func (c *Cache[K, T]) loadOrStore(key K, query QueryFunc[K, T]) (Item[T], []eviction[K, T], error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	item, ok := c.lookup(key, c.touchOnGet())
+	if ok {
+		c.hits.Add(1)
+
+		return item, nil, nil
+	}
+
+	c.misses.Add(1)
+
+	start := time.Now()
+	data, err := c.query(context.Background(), key, query.withContext())
+
+	c.queries.Add(1)
+	c.queryDuration.Add(int64(time.Since(start)))
+
+	if err != nil {
+		return Item[T]{}, nil, fmt.Errorf("failed to query data: %w", err)
+	}
+
+	ttl := c.DefaultTTL()
+
+	itemTTL, ok := c.zeroValueTTL(data, ttl)
+	if !ok {
+		return c.newItem(data, ttl), nil, nil
+	}
+
+	item = c.newItem(data, itemTTL)
+	evicted := c.set(key, item, itemTTL)
+
+	return item, evicted, nil
+}
+
+// RememberContext tries to get the Item from the Cache, if the Item is not found or expired QueryContextFunc
+// is called with the given context to retrieve the data from source and put it into the Cache.
+// If the context is canceled before QueryContextFunc returns, RememberContext returns early with the error
+// of the context.
+// Concurrent calls for the same key are deduplicated, so QueryContextFunc is called only once and all callers
+// receive the same result. It is called with the values of the context of the first caller, but is not canceled
+// with it, so the other callers are not affected if the first caller returns early.
+func (c *Cache[K, T]) RememberContext(ctx context.Context, key K, query QueryContextFunc[K, T]) (Item[T], error) {
+	item, _, err := c.rememberContext(ctx, key, query, rememberOptions[T]{ttl: c.DefaultTTL()})
+	return item, err
+}
+
+// WarmUp calls QueryFunc for all keys with up to concurrency calls at once and puts the results into the Cache
+// with the default time-to-live, regardless of whether an Item is already cached. Calls for keys which are
+// queried concurrently by a Remember method are deduplicated. The errors of all failed calls are joined
+// and returned. If the context of the Cache is canceled, no further calls are made and the error
+// of the context is returned as well.
+func (c *Cache[K, T]) WarmUp(keys []K, query QueryFunc[K, T], concurrency int) error {
+	var (
+		wg   sync.WaitGroup
+		mut  sync.Mutex
+		errs []error
+	)
+
+	sem := make(chan struct{}, max(concurrency, 1))
+	opts := rememberOptions[T]{ttl: c.DefaultTTL()}
+
+	for _, key := range keys {
+		if c.ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-c.ctx.Done():
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			_, _, err := c.do(c.ctx, key, c.fetch(context.WithoutCancel(c.ctx), key, query.withContext(), opts))
+			if err != nil {
+				mut.Lock()
+				errs = append(errs, fmt.Errorf("failed to warm up key %v: %w", key, err))
+				mut.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if err := c.ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// rememberOptions alters the behavior of rememberContext.
+type rememberOptions[T any] struct {
+	// ttl is the time-to-live of the queried Item.
+	ttl time.Duration
+
+	// negative enables caching of negative results with negativeTTL.
+	negative    bool
+	negativeTTL time.Duration
+
+	// shouldCache decides whether the queried data is put into the Cache, if set.
+	shouldCache func(value T) bool
+}
+
+// rememberContext implements the Remember methods. Additionally to the Item, it returns true
+// if the result has been served from the Cache without waiting for the query.
+func (c *Cache[K, T]) rememberContext(ctx context.Context, key K, query QueryContextFunc[K, T], opts rememberOptions[T]) (Item[T], bool, error) {
+	item, ok := c.Get(key)
+	if ok {
+		if c.cfg.RenewOnRemember {
+			item = c.renew(key, item)
+		}
+
+		return item, true, nil
+	}
+
+	if c.negativeCached(key) {
+		return Item[T]{}, true, ErrCachedNotFound
+	}
+
+	if c.cfg.StaleWhileRevalidate {
+		item, ok = c.stale(key)
+		if ok {
+			// the refresh must not be canceled when the caller returns
+			c.start(key, c.fetch(context.WithoutCancel(ctx), key, query, opts))
+
+			return item, true, nil
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return Item[T]{}, false, err
+	}
+
+	// the query is shared with concurrent callers, so it must not be canceled if this caller returns early,
+	// every caller stops waiting for it on the cancellation of its own context in do
+	fetch := c.fetch(context.WithoutCancel(ctx), key, query, opts)
+
+	return c.do(ctx, key, func(wait context.Context) (Item[T], bool, error) {
+		// a call for the key which completed after the lookup above might have stored the Item already
+		if item, ok := c.Peek(key); ok {
+			return item, true, nil
+		}
+
+		return fetch(wait)
+	})
+}
+
+// fetch returns a function which calls the query and puts its result into the Cache.
+// The function gives up waiting for a slot of MaxConcurrentQueries once the wait context is canceled,
+// which happens if all callers have stopped waiting for the call, see leave.
+func (c *Cache[K, T]) fetch(ctx context.Context, key K, query QueryContextFunc[K, T], opts rememberOptions[T]) func(wait context.Context) (Item[T], bool, error) {
+	return func(wait context.Context) (Item[T], bool, error) {
+		if c.queryLimit != nil {
+			select {
+			case c.queryLimit <- struct{}{}:
+				defer func() { <-c.queryLimit }()
+			case <-wait.Done():
+				return Item[T]{}, false, fmt.Errorf("failed to query data: %w", context.Cause(wait))
+			}
+
+			// the call might have been abandoned while the slot became free
+			if wait.Err() != nil {
+				return Item[T]{}, false, fmt.Errorf("failed to query data: %w", context.Cause(wait))
+			}
+		}
+
+		start := time.Now()
+		data, err := c.query(ctx, key, query)
+
+		c.queries.Add(1)
+		c.queryDuration.Add(int64(time.Since(start)))
+
+		if err != nil {
+			if opts.negative && errors.Is(err, ErrNotFound) {
+				c.mut.Lock()
+				c.negatives[key] = c.expiration(opts.negativeTTL)
+				c.mut.Unlock()
+			}
+
+			return Item[T]{}, false, fmt.Errorf("failed to query data: %w", err)
+		}
+
+		if opts.shouldCache != nil && !opts.shouldCache(data) {
+			return c.newItem(data, opts.ttl), false, nil
+		}
+
+		ttl, ok := c.zeroValueTTL(data, opts.ttl)
+		if !ok {
+			return c.newItem(data, opts.ttl), false, nil
+		}
+
+		item := c.store(key, data, ttl)
+
+		if c.cfg.RefreshAhead > 0 {
+			// the refresh must not be canceled when the caller returns
+			c.registerRefresh(key, c.fetch(context.WithoutCancel(ctx), key, query, opts))
+		}
+
+		return item, false, nil
+	}
+}
+
+// zeroValueTTL returns the time-to-live to cache the queried data with according to the ZeroValuePolicy
+// and false if the data must not be cached.
+func (c *Cache[K, T]) zeroValueTTL(data T, ttl time.Duration) (time.Duration, bool) {
+	if c.cfg.ZeroValuePolicy == CacheZeroValues || !reflect.ValueOf(&data).Elem().IsZero() {
+		return ttl, true
+	}
+
+	if c.cfg.ZeroValuePolicy == ShortTTLZeroValues && c.cfg.ZeroValueTTL > 0 {
+		return c.cfg.ZeroValueTTL, true
+	}
+
+	return 0, false
+}
+
+// renew resets the expiration of the Item to its original time-to-live and returns the renewed Item.
+// The given Item is returned if the Item has been removed, expired or does not expire in the meantime.
+func (c *Cache[K, T]) renew(key K, item Item[T]) Item[T] {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	e, ok := c.data[key]
+	if !ok || c.expired(&e.item) || e.item.TTL == 0 {
+		return item
+	}
+
+	e.item.TTL = c.expiration(e.ttl)
+	c.updateExpiry(e)
+
+	return e.item
+}
+
+// registerRefresh sets the function to refresh the Item ahead of its expiration.
+func (c *Cache[K, T]) registerRefresh(key K, refresh func(wait context.Context) (Item[T], bool, error)) {
+	c.mut.Lock()
+	if e, ok := c.data[key]; ok {
+		e.refresh = refresh
+	}
+	c.mut.Unlock()
+}
+
+// query calls the query and abandons it if the QueryTimeout is exceeded.
+// The result of an abandoned query is discarded.
+func (c *Cache[K, T]) query(ctx context.Context, key K, query QueryContextFunc[K, T]) (T, error) {
+	if c.cfg.QueryTimeout <= 0 {
+		return query(ctx, key)
+	}
+
+	ctx, cancel := context.WithTimeoutCause(ctx, c.cfg.QueryTimeout, ErrQueryTimeout)
+	defer cancel()
+
+	type result struct {
+		data     T
+		err      error
+		panicked any
+	}
+
+	// buffered, so the query does not block if it has been abandoned
+	results := make(chan result, 1)
+
+	go func() {
+		var r result
+
+		// a panic is raised again in the caller, unless the query has been abandoned
+		defer func() {
+			r.panicked = recover()
+			results <- r
+		}()
+
+		r.data, r.err = query(ctx, key)
+	}()
+
+	select {
+	case r := <-results:
+		if r.panicked != nil {
+			panic(r.panicked)
+		}
+
+		return r.data, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, context.Cause(ctx)
+	}
+}
+
+// stale returns an expired Item and true if the Item was found in the Cache and is still within the GracePeriod.
+func (c *Cache[K, T]) stale(key K) (Item[T], bool) {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	e, ok := c.data[key]
+	if !ok || !c.expired(&e.item) || c.removable(&e.item) {
+		return Item[T]{}, false
+	}
+
+	return c.copied(e.item), true
+}
+
+// negativeCached returns true if a negative result for the key has been cached and has not been expired.
+func (c *Cache[K, T]) negativeCached(key K) bool {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	ttl, ok := c.negatives[key]
+
+	return ok && !c.expiredAt(ttl)
+}
+
+// call is an in-flight or completed invocation of a QueryFunc.
+type call[T any] struct {
+	done chan struct{}
+	item Item[T]
+	err  error
+
+	// cached is true if fn has found the Item in the Cache without calling the QueryFunc.
+	cached bool
+
+	// panicked holds the value fn panicked with, which is raised again in every caller waiting for the call.
+	panicked any
+
+	// waiters is the number of callers of do waiting for the call and background is true if the call has been
+	// started without waiting for it, both guarded by callsMut. abandon cancels the context passed to fn.
+	waiters    int
+	background bool
+	abandon    context.CancelCauseFunc
+}
+
+// do executes fn and returns its result, making sure only one execution is in-flight for the key at a time.
+// If a duplicate call comes in, the caller waits for the original call to complete and receives the same result,
+// with its own copy of the data if a copier is set. The bool reports if fn has found the Item in the Cache.
+// If the context is canceled, do returns early with the error of the context while fn keeps running.
+func (c *Cache[K, T]) do(ctx context.Context, key K, fn func(wait context.Context) (Item[T], bool, error)) (Item[T], bool, error) {
+	for {
+		cl := c.join(key, fn)
+
+		select {
+		case <-cl.done:
+			if cl.panicked != nil {
+				panic(cl.panicked)
+			}
+
+			if errors.Is(cl.err, errAbandoned) {
+				// joined the call after all other waiters had stopped waiting for it, so a new call is started
+				continue
+			}
+
+			if cl.err != nil {
+				return Item[T]{}, false, cl.err
+			}
+
+			return c.copied(cl.item), cl.cached, nil
+		case <-ctx.Done():
+			c.leave(cl)
+
+			return Item[T]{}, false, ctx.Err()
+		}
+	}
+}
+
+// start executes fn in the background unless an execution is already in-flight for the key
+// and returns the call. As nobody waits for it, the call is never abandoned.
+func (c *Cache[K, T]) start(key K, fn func(wait context.Context) (Item[T], bool, error)) *call[T] {
+	c.callsMut.Lock()
+	defer c.callsMut.Unlock()
+
+	cl := c.launch(key, fn)
+	cl.background = true
+
+	return cl
+}
+
+// join works like start, but registers the caller as a waiter of the call.
+func (c *Cache[K, T]) join(key K, fn func(wait context.Context) (Item[T], bool, error)) *call[T] {
+	c.callsMut.Lock()
+	defer c.callsMut.Unlock()
+
+	cl := c.launch(key, fn)
+	cl.waiters++
+
+	return cl
+}
+
+// leave unregisters a waiter which has stopped waiting for the call. If no waiter is left and the call
+// has not been started in the background, the call is abandoned, so a query still waiting for a slot of
+// MaxConcurrentQueries is not run anymore. A query which is already running is not canceled.
+func (c *Cache[K, T]) leave(cl *call[T]) {
+	c.callsMut.Lock()
+	defer c.callsMut.Unlock()
+
+	cl.waiters--
+	if cl.waiters > 0 || cl.background {
+		return
+	}
+
+	cl.abandon(errAbandoned)
+}
+
+// launch executes fn in a new goroutine unless an execution is already in-flight for the key
+// and returns the call to wait for. A panic in fn is recovered, so it does not crash the program
+// if nobody waits for the call, e.g. for a refresh in the background, and is logged with the Logger.
+// The caller must hold callsMut.
+func (c *Cache[K, T]) launch(key K, fn func(wait context.Context) (Item[T], bool, error)) *call[T] {
+	if cl, ok := c.calls[key]; ok {
+		return cl
+	}
+
+	wait, abandon := context.WithCancelCause(context.Background())
+
+	cl := &call[T]{done: make(chan struct{}), abandon: abandon}
+	c.calls[key] = cl
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				cl.panicked = r
+
+				if c.cfg.Logger != nil {
+					c.cfg.Logger.Printf("mempot: recovered from panic in query of key %v: %v", key, r)
+				}
+			}
+
+			c.callsMut.Lock()
+			delete(c.calls, key)
+			c.callsMut.Unlock()
+
+			abandon(nil)
+			close(cl.done)
+		}()
+
+		cl.item, cl.cached, cl.err = fn(wait)
+	}()
+
+	return cl
+}
+
+// Delete removes an Item from the Cache.
+func (c *Cache[K, T]) Delete(key K) {
+	c.mut.Lock()
+	if c.remove(key) {
+		c.publish(EventDelete, key)
+	}
+	c.mut.Unlock()
+}
+
+// GetAndDelete removes an Item from the Cache and returns it with true if it was found and has not been expired.
+// The lookup and the removal happen atomically, so the Item is returned to a single caller only.
+// An expired Item is removed as well if it has been expired for longer than the GracePeriod, but false is returned.
+func (c *Cache[K, T]) GetAndDelete(key K) (Item[T], bool) {
+	c.mut.Lock()
+
+	e, ok := c.data[key]
+	if !ok {
+		c.mut.Unlock()
+		c.misses.Add(1)
+
+		return Item[T]{}, false
+	}
+
+	if c.expired(&e.item) {
+		removable := c.removable(&e.item)
+		if removable {
+			c.remove(key)
+			c.publish(EventExpire, key)
+		}
+		c.mut.Unlock()
+
+		c.misses.Add(1)
+
+		if removable {
+			c.evictions.Add(1)
+			c.notifyExpired([]eviction[K, T]{{key: key, value: e.item.Data}})
+		}
+
+		return Item[T]{}, false
+	}
+
+	c.remove(key)
+	c.publish(EventDelete, key)
+	c.mut.Unlock()
+
+	c.hits.Add(1)
+
+	return e.item, true
+}
+
+// DeleteMany removes multiple Items from the Cache while acquiring the lock only once.
+// Keys which are not found in the Cache are ignored.
+func (c *Cache[K, T]) DeleteMany(keys []K) {
+	c.mut.Lock()
+	for _, key := range keys {
+		if c.remove(key) {
+			c.publish(EventDelete, key)
+		}
+	}
+	c.mut.Unlock()
+}
+
+// InvalidateBefore removes all Items from the Cache which have been set before t, e.g. after the format
+// of the cached data has changed, and returns the number of removed Items.
+// Modifying the data with Update or CompareAndSwap does not change when an Item has been set.
+func (c *Cache[K, T]) InvalidateBefore(t time.Time) int {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	removed := 0
+
+	for key, e := range c.data {
+		if !time.Unix(0, e.item.CreatedAt).Before(t) {
+			continue
+		}
+
+		c.remove(key)
+		c.publish(EventDelete, key)
+
+		removed++
+	}
+
+	return removed
+}
+
+// Len returns the number of Items in the Cache.
+// Expired Items which have not been removed by a cleanup yet are counted as well.
+// Len does not trigger a cleanup.
+func (c *Cache[K, T]) Len() int {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	return len(c.data)
+}
+
+// Keys returns the keys of all Items in the Cache which have not been expired.
+// The returned slice is a point-in-time snapshot in no particular order and may be stale immediately.
+func (c *Cache[K, T]) Keys() []K {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	keys := make([]K, 0, len(c.data))
+
+	for key, e := range c.data {
+		if c.expired(&e.item) {
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// GetAll returns a copy of all Items in the Cache which have not been expired by their key.
+// The returned map is a point-in-time snapshot which may be stale immediately, modifying it does not affect the Cache.
+// GetAll does not affect the statistics and does not mark the Items as recently used.
+func (c *Cache[K, T]) GetAll() map[K]Item[T] {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	items := make(map[K]Item[T], len(c.data))
+
+	for key, e := range c.data {
+		if c.expired(&e.item) {
+			continue
+		}
+
+		items[key] = c.copied(e.item)
+	}
+
+	return items
+}
+
+// Values returns the data of all Items in the Cache which have not been expired.
+// The returned slice is a point-in-time snapshot in no particular order and may be stale immediately.
+func (c *Cache[K, T]) Values() []T {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	values := make([]T, 0, len(c.data))
+
+	for _, e := range c.data {
+		if c.expired(&e.item) {
+			continue
+		}
+
+		values = append(values, c.copied(e.item).Data)
+	}
+
+	return values
+}
+
+// Dump returns a human-readable listing of all Items in the Cache including the expired Items
+// which have not been removed yet, one line per Item sorted by key, showing the key, the remaining
+// time-to-live and the data formatted with %v. It is intended for debugging only.
+func (c *Cache[K, T]) Dump() string {
+	c.mut.RLock()
+
+	lines := make([]string, 0, len(c.data))
+	now := c.now()
+
+	for key, e := range c.data {
+		var ttl string
+
+		switch {
+		case e.item.TTL == 0:
+			ttl = "no expiration"
+		case c.expired(&e.item):
+			ttl = "expired"
+		default:
+			ttl = time.Unix(0, e.item.TTL).Sub(now).Round(time.Millisecond).String()
+		}
+
+		lines = append(lines, fmt.Sprintf("%v\t%s\t%v", key, ttl, e.item.Data))
+	}
+
+	c.mut.RUnlock()
+
+	slices.Sort(lines)
+
+	return strings.Join(lines, "\n")
+}
+
+// All returns an iterator over all Items in the Cache which have not been expired, in no particular order,
+// to be used with a for-range loop. The Items are copied when the iteration starts and the lock is not held
+// while iterating, so the Cache can be modified from within the loop, but the changes are not visible to it.
+func (c *Cache[K, T]) All() iter.Seq2[K, Item[T]] {
+	return func(yield func(key K, item Item[T]) bool) {
+		for key, item := range c.GetAll() {
+			if !yield(key, item) {
+				return
+			}
+		}
+	}
+}
+
+// Range calls fn for every Item in the Cache which has not been expired, in no particular order.
+// If fn returns false, Range stops the iteration.
+// The read lock is held during the iteration, so calling any method of the Cache which modifies it
+// from within fn will deadlock. Collect the keys first and modify the Cache afterwards instead.
+func (c *Cache[K, T]) Range(fn func(key K, item Item[T]) bool) {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	for key, e := range c.data {
+		if c.expired(&e.item) {
+			continue
+		}
+
+		if !fn(key, c.copied(e.item)) {
+			return
+		}
+	}
+}
+
+// ForEachParallel calls fn for every Item in the Cache which has not been expired using up to concurrency
+// goroutines and returns when all calls have returned. The Items are copied while holding the read lock,
+// which is released before fn is called, so fn may access and modify the Cache and changes made
+// after the copy are not visible to the iteration.
+func (c *Cache[K, T]) ForEachParallel(concurrency int, fn func(key K, value T)) {
+	items := c.GetAll()
+
+	type pair struct {
+		key   K
+		value T
+	}
+
+	pairs := make(chan pair)
+
+	var wg sync.WaitGroup
+	for range min(max(concurrency, 1), max(len(items), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for p := range pairs {
+				fn(p.key, p.value)
+			}
+		}()
+	}
+
+	for key, item := range items {
+		pairs <- pair{key: key, value: item.Data}
+	}
+
+	close(pairs)
+	wg.Wait()
+}
+
+// Clone returns a new independent Cache with the same Config, callbacks and copies of all Items
+// which have not been expired. The order of recently used Items is preserved.
+// The clone has its own cleanup goroutine which is stopped when the context of the Cache is canceled.
+// If TTLJitter is used, the clone gets its own randomly seeded JitterSource.
+func (c *Cache[K, T]) Clone() *Cache[K, T] {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	cfg := c.cfg
+	cfg.DefaultTTL = c.DefaultTTL()
+	cfg.MaxItems = int(c.maxItems.Load())
+	cfg.JitterSource = nil
+
+	clone := NewCache[K, T](c.ctx, cfg)
+
+	// a CleanupInterval of 0 would be replaced by the default
+	if cfg.CleanupInterval == 0 {
+		clone.SetCleanupInterval(0)
+	}
+
+	// the cleanup goroutine of the clone is already running
+	clone.mut.Lock()
+	defer clone.mut.Unlock()
+
+	clone.onEvict = c.onEvict
+	clone.onExpire = c.onExpire
+	clone.onCleanup = c.onCleanup
+	clone.sizer = c.sizer
+	clone.copier = c.copier
+	clone.marshal = c.marshal
+	clone.unmarshal = c.unmarshal
+
+	if c.indexes != nil {
+		clone.indexes = make(map[string]*secondaryIndex[K, T], len(c.indexes))
+	}
+
+	for name, idx := range c.indexes {
+		clone.indexes[name] = newSecondaryIndex[K, T](idx.extract)
+	}
+
+	for elem := c.lru.Back(); elem != nil; elem = elem.Prev() {
+		key := elem.Value.(K)
+		e := c.data[key]
+
+		if c.expired(&e.item) {
+			continue
+		}
+
+		cloned := &entry[K, T]{key: key, item: e.item, elem: clone.lru.PushFront(key), ttl: e.ttl, size: e.size, accesses: e.accesses, index: -1}
+		clone.data[key] = cloned
+		clone.bytes += e.size
+		clone.indexAdd(key, e.item.Data)
+		clone.updateExpiry(cloned)
+	}
+
+	return clone
+}
+
+// OnEvict sets a callback which is called for every Item which is removed because it has been expired,
+// like OnExpire, or which is evicted because MaxItems or MaxBytes was exceeded or by CloseAndEvict.
+// The callback is not called for Items removed by Delete, DeleteMany, DeleteFunc, GetAndDelete of an Item
+// which has not been expired, InvalidateBefore, Tx.Delete or Reset.
+// The callback is called without holding the lock of the Cache, so it is safe to access the Cache from within,
+// except for Close and SetCleanupInterval, which wait for the cleanup goroutine that may be calling it.
+// A panic in the callback is recovered and logged with the Logger.
+func (c *Cache[K, T]) OnEvict(fn func(key K, value T)) {
+	c.mut.Lock()
+	c.onEvict = fn
+	c.mut.Unlock()
+}
+
+// DefaultTTL returns the time-to-live used for Items which are added without an explicit time-to-live.
+func (c *Cache[K, T]) DefaultTTL() time.Duration {
+	return time.Duration(c.defaultTTL.Load())
+}
+
+// SetDefaultTTL changes the time-to-live used for Items which are added without an explicit time-to-live
+// from now on. The expiration of Items already in the Cache is not changed.
+// If set to 0, Items added without an explicit time-to-live will not expire.
+func (c *Cache[K, T]) SetDefaultTTL(ttl time.Duration) {
+	c.defaultTTL.Store(int64(ttl))
+}
+
+// Resize changes MaxItems at runtime. If the Cache holds more than n Items, the excess Items are evicted
+// immediately according to the EvictionPolicy and passed to the OnEvict callback.
+// If n is 0, the number of Items is not limited anymore.
+func (c *Cache[K, T]) Resize(n int) {
+	c.mut.Lock()
+	c.maxItems.Store(int64(max(n, 0)))
+	evicted := c.evict()
+	c.mut.Unlock()
+
+	c.notifyEvicted(evicted)
+}
+
+// SetCopier sets a function which returns a deep copy of the data, e.g. if T is a pointer, slice or map.
+// If set, the data is copied when an Item is stored and when it is returned by Get, Peek, GetStale, GetAll,
+// Values, Range and the Remember methods, so callers can modify the data without affecting the Cache or other
+// callers. This prevents data races on shared data, but costs a copy on every read and write.
+// The functions of this package like Map or Filter and the callbacks receive the cached data without copying it.
+func (c *Cache[K, T]) SetCopier(fn func(value T) T) {
+	c.mut.Lock()
+	c.copier = fn
+	c.mut.Unlock()
+}
+
+// copied returns the Item with a copy of its data if a copier has been set.
+// The caller must hold at least the read lock.
+func (c *Cache[K, T]) copied(item Item[T]) Item[T] {
+	if c.copier != nil {
+		item.Data = c.copier(item.Data)
+	}
+
+	return item
+}
+
+// SetSizer sets the function used to estimate the size of an Item in bytes, which is required for MaxBytes.
+// The sizes of all Items already in the Cache are estimated again and Items are evicted if MaxBytes is exceeded.
+func (c *Cache[K, T]) SetSizer(fn func(value T) int64) {
+	c.mut.Lock()
+
+	c.sizer = fn
+	c.bytes = 0
+
+	for _, e := range c.data {
+		e.size = c.sizeOf(e.item.Data)
+		c.bytes += e.size
+	}
+
+	evicted := c.evict()
+	c.mut.Unlock()
+
+	c.notifyEvicted(evicted)
+}
+
+// TTLDistribution counts the Items in the Cache which have not been expired by their remaining time-to-live.
+// Every Item is counted in the smallest bucket which is greater than or equal to its remaining time-to-live,
+// so the counts are not cumulative. Items which will not expire are counted in the NoExpiration bucket,
+// Items whose remaining time-to-live exceeds the largest bucket are not counted. The buckets do not need to be sorted.
+func (c *Cache[K, T]) TTLDistribution(buckets []time.Duration) map[time.Duration]int {
+	sorted := slices.Clone(buckets)
+	slices.Sort(sorted)
+
+	counts := make(map[time.Duration]int, len(sorted)+1)
+	for _, bucket := range sorted {
+		counts[bucket] = 0
+	}
+
+	counts[NoExpiration] = 0
+
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	now := c.now()
+
+	for _, e := range c.data {
+		if e.item.expiredAt(now) {
+			continue
+		}
+
+		if e.item.TTL == 0 {
+			counts[NoExpiration]++
+			continue
+		}
+
+		remaining := time.Unix(0, e.item.TTL).Sub(now)
+
+		i, _ := slices.BinarySearch(sorted, remaining)
+		if i < len(sorted) {
+			counts[sorted[i]]++
+		}
+	}
+
+	return counts
+}
+
+// MemoryUsage returns a rough estimate of the memory in bytes held by all Items in the Cache which have not been
+// expired. It is the sum of the sizes estimated by the function set with SetSizer plus the internal overhead
+// for each Item. Without a sizer, only the shallow size of the data is counted, memory referenced by pointers,
+// slices, maps or strings is not included.
+func (c *Cache[K, T]) MemoryUsage() int64 {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	var usage int64
+
+	for _, e := range c.data {
+		if c.expired(&e.item) {
+			continue
+		}
+
+		usage += e.size + entryOverhead[K, T]()
+	}
+
+	return usage
+}
+
+// entryOverhead returns the estimated size in bytes of the internal structures holding an Item,
+// including the shallow size of its key and data.
+func entryOverhead[K comparable, T any]() int64 {
+	var (
+		e   entry[K, T]
+		key K
+	)
+
+	// the entry, its element in the least recently used list and the key and pointer stored in the map
+	return int64(unsafe.Sizeof(e) + unsafe.Sizeof(list.Element{}) + unsafe.Sizeof(key) + unsafe.Sizeof(&e))
+}
+
+// OnExpire sets a callback which is called for every expired Item which is removed by a cleanup,
+// by Get or GetOrdered if LazyExpiration is enabled or by GetAndDelete. Unlike OnEvict, it is not called
+// for Items evicted because MaxItems or MaxBytes was exceeded. As an expired Item is removed only once,
+// the callback is called at most once per Item. If both are set, OnExpire is called before OnEvict.
+// The callback is called without holding the lock of the Cache, so it is safe to access the Cache from within,
+// except for Close and SetCleanupInterval, which wait for the cleanup goroutine that may be calling it.
+// A panic in the callback is recovered and logged with the Logger.
+func (c *Cache[K, T]) OnExpire(fn func(key K, value T)) {
+	c.mut.Lock()
+	c.onExpire = fn
+	c.mut.Unlock()
+}
+
+// OnCleanup sets a callback which is called once per cleanup with the number of Items in the Cache
+// when the cleanup started and the number of expired Items it removed, e.g. to report metrics without
+// running a separate ticker. It is called for the cleanups of the cleanup goroutine and for calling Cleanup.
+// The callback is called without holding the lock of the Cache, so it is safe to access the Cache from within,
+// except for Close and SetCleanupInterval, which wait for the cleanup goroutine that may be calling it.
+// A panic in the callback is recovered and logged with the Logger.
+func (c *Cache[K, T]) OnCleanup(fn func(scanned, evicted int)) {
+	c.mut.Lock()
+	c.onCleanup = fn
+	c.mut.Unlock()
+}
+
+// Stats returns the current statistics of the Cache.
+func (c *Cache[K, T]) Stats() Stats {
+	return Stats{
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Evictions:     c.evictions.Load(),
+		Queries:       c.queries.Load(),
+		QueryDuration: time.Duration(c.queryDuration.Load()),
+	}
+}
+
+// Reset removes all Items from the Cache.
+func (c *Cache[K, T]) Reset() {
+	c.ResetWithCapacity(0)
+}
+
+// ResetWithCapacity removes all Items from the Cache and allocates space for n Items,
+// which avoids growing the Cache if it is refilled with a similar number of Items.
+// If n is 0, the InitialCapacity is used like on Reset.
+func (c *Cache[K, T]) ResetWithCapacity(n int) {
+	if n <= 0 {
+		n = c.cfg.InitialCapacity
+	}
+
+	c.mut.Lock()
+	c.data = make(map[K]*entry[K, T], n)
+	c.negatives = make(map[K]int64)
+	c.lru.Init()
+	c.expiries = nil
+	c.bytes = 0
+
+	for name, idx := range c.indexes {
+		c.indexes[name] = newSecondaryIndex[K, T](idx.extract)
+	}
+
+	c.mut.Unlock()
+}
+
+// Cleanup synchronously removes all Items which have been expired for longer than the GracePeriod,
+// like the cleanup goroutine does on every CleanupInterval.
+// This allows to reclaim memory on demand, e.g. if the cleanup goroutine is disabled.
+func (c *Cache[K, T]) Cleanup() {
+	c.cleanup()()
+}
+
+// cleanup removes the expired Items and returns a function which calls the callbacks for them,
+// so the cleanup goroutine can call them after releasing scanMut.
+func (c *Cache[K, T]) cleanup() func() {
+	var expired []eviction[K, T]
+
+	c.mut.Lock()
+
+	scanned := len(c.data)
+
+	for key, ttl := range c.negatives {
+		if c.expiredAt(ttl) {
+			delete(c.negatives, key)
+		}
+	}
+
+	cutoff := c.now().Add(-c.cfg.GracePeriod)
+
+	// only the expired entries at the top of the heap are visited
+	for n := 0; len(c.expiries) > 0 && c.expiries[0].item.expiredAt(cutoff); n++ {
+		if c.cfg.CleanupBatchSize > 0 && n > 0 && n%c.cfg.CleanupBatchSize == 0 {
+			// let waiting operations proceed between batches
+			c.mut.Unlock()
+			c.mut.Lock()
+		}
+
+		e := c.expiries[0]
+		expired = append(expired, eviction[K, T]{key: e.key, value: e.item.Data})
+		c.remove(e.key)
+		c.publish(EventExpire, e.key)
+	}
+
+	var refreshes []*entry[K, T]
+	if c.cfg.RefreshAhead > 0 {
+		refreshes = c.expiries.before(c.now().Add(c.cfg.RefreshAhead).UnixNano())
+	}
+
+	for _, e := range refreshes {
+		if e.refresh != nil && !c.expired(&e.item) {
+			c.start(e.key, e.refresh)
+		}
+	}
+
+	onCleanup := c.onCleanup
+
+	c.mut.Unlock()
+
+	c.evictions.Add(uint64(len(expired)))
+
+	return func() {
+		if c.cfg.Logger != nil && len(expired) > 0 {
+			c.cfg.Logger.Printf("mempot: cleanup removed %d expired items", len(expired))
+		}
+
+		c.notifyExpired(expired)
+
+		if onCleanup != nil {
+			c.callback("OnCleanup", func() {
+				onCleanup(scanned, len(expired))
+			})
+		}
+	}
+}
+
+// Compact releases the memory held by the Cache after a large number of Items has been removed,
+// as the internal map does not shrink by itself. Expired Items are removed first like by Cleanup,
+// then the remaining Items are copied into a new map sized for them. The write lock is held while copying,
+// so Compact should only be called occasionally, e.g. after a bulk deletion.
+func (c *Cache[K, T]) Compact() {
+	c.Cleanup()
+
+	c.mut.Lock()
+	data := make(map[K]*entry[K, T], max(len(c.data), c.cfg.InitialCapacity))
+	maps.Copy(data, c.data)
+	c.data = data
+	c.mut.Unlock()
+}
+
+// Close stops the cleanup goroutine and waits until it has stopped and all writes of AsyncSet have been applied.
+// The Cache can still be used afterwards, but expired Items are only removed by calling Cleanup.
+// The OnEvict and OnExpire callbacks are not called for the remaining Items, see CloseAndEvict.
+// Close is idempotent and safe to be called multiple times. It must not be called from the OnEvict, OnExpire
+// or OnCleanup callbacks, as it would wait for the cleanup goroutine calling them.
+func (c *Cache[K, T]) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+
+	// if AsyncSet has never been called, no goroutine is running which applies the writes
+	c.asyncOnce.Do(func() {
+		c.asyncMut.Lock()
+		c.asyncStopped = true
+		c.asyncMut.Unlock()
+
+		c.asyncDone = make(chan struct{})
+		close(c.asyncDone)
+	})
+
+	<-c.asyncDone
+
+	c.cleanupMut.Lock()
+	done := c.cleanupDone
+	c.cleanupMut.Unlock()
+
+	<-done
+}
+
+// CloseAndEvict works like Close, but afterwards removes all remaining Items from the Cache and calls the
+// OnEvict callback for each of them, e.g. to release resources held by the cached data.
+// Like Close, it must not be called from the OnEvict, OnExpire or OnCleanup callbacks.
+func (c *Cache[K, T]) CloseAndEvict() {
+	c.Close()
+
+	c.mut.Lock()
+	evicted := make([]eviction[K, T], 0, len(c.data))
+
+	for key, e := range c.data {
+		evicted = append(evicted, eviction[K, T]{key: key, value: e.item.Data})
+		c.remove(key)
+		c.publish(EventEvict, key)
+	}
+	c.mut.Unlock()
+
+	c.evictions.Add(uint64(len(evicted)))
+	c.notifyEvicted(evicted)
+}
+
+// SetCleanupInterval changes the interval of the cleanup goroutine, which starts a new interval immediately.
+// If no cleanup goroutine is running, it is started. If the interval is 0, the cleanup goroutine is stopped
+// and expired Items are only removed by calling Cleanup. SetCleanupInterval has no effect after Close has been
+// called or the context of the Cache has been canceled. Like Close, it must not be called from the OnEvict,
+// OnExpire or OnCleanup callbacks.
+func (c *Cache[K, T]) SetCleanupInterval(interval time.Duration) {
+	c.cleanupMut.Lock()
+	defer c.cleanupMut.Unlock()
+
+	select {
+	case <-c.closed:
+		return
+	case <-c.ctx.Done():
+		return
+	default:
+	}
+
+	c.mut.Lock()
+	c.cfg.CleanupInterval = max(interval, 0)
+	c.mut.Unlock()
+
+	select {
+	case c.intervals <- interval:
+		if interval <= 0 {
+			<-c.cleanupDone
+		}
+
+		return
+	case <-c.cleanupDone:
+		// no cleanup goroutine is running
+	}
+
+	if interval > 0 {
+		c.cleanupDone = make(chan struct{})
+		go c.runCleanup(interval, c.cleanupDone)
+	}
+}
+
+// PauseCleanup pauses the cleanup goroutine, which skips its cleanups until ResumeCleanup is called,
+// e.g. to keep expired Items while the Cache is rebuilt. Unlike setting the CleanupInterval to 0,
+// the cleanup goroutine keeps running. If a cleanup is removing expired Items, PauseCleanup waits until it has
+// finished, but not for its callbacks, so it can be called from within them.
+// Calling Cleanup still removes expired Items while the cleanup goroutine is paused.
+func (c *Cache[K, T]) PauseCleanup() {
+	c.paused.Store(true)
+
+	// waits for a running cleanup, the next one sees paused
+	c.scanMut.Lock()
+	c.scanMut.Unlock()
+}
+
+// ResumeCleanup resumes the cleanup goroutine after PauseCleanup, starting with its next interval.
+func (c *Cache[K, T]) ResumeCleanup() {
+	c.paused.Store(false)
+}
+
+func (c *Cache[K, T]) runCleanup(interval time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// timer fires when the Item which expires next can be removed
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	// last is the time of the last cleanup
+	var last time.Time
+
+	c.schedule(timer, last)
+
+	defer close(done)
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.closed:
+			return
+		case interval := <-c.intervals:
+			if interval <= 0 {
+				return
+			}
+
+			ticker.Reset(interval)
+		case <-c.wake:
+			c.schedule(timer, last)
+		case <-ticker.C:
+			c.tick(timer, &last)
+		case <-timer.C:
+			c.tick(timer, &last)
+		}
+	}
+}
+
+// tick runs a cleanup of the cleanup goroutine unless it is paused and schedules the next one.
+// While paused, the timer is not reset, so only the ticker wakes the cleanup goroutine.
+func (c *Cache[K, T]) tick(timer *time.Timer, last *time.Time) {
+	c.scanMut.Lock()
+	if c.paused.Load() {
+		c.scanMut.Unlock()
+		return
+	}
+
+	notify := c.cleanup()
+	c.scanMut.Unlock()
+
+	*last = time.Now()
+
+	notify()
+
+	c.schedule(timer, *last)
+}
+
+// schedule resets the timer to fire when the Item which expires next has been expired for longer than
+// the GracePeriod, but not earlier than minCleanupDelay after the last cleanup. The timer is stopped
+// if no Item expires.
+func (c *Cache[K, T]) schedule(timer *time.Timer, last time.Time) {
+	c.mut.RLock()
+	if len(c.expiries) == 0 {
+		c.mut.RUnlock()
+		timer.Stop()
+
+		return
+	}
+
+	// an Item is expired once the current time is after its TTL
+	due := time.Unix(0, c.expiries[0].item.TTL+1).Add(c.cfg.GracePeriod)
+	delay := due.Sub(c.now())
+	c.mut.RUnlock()
+
+	timer.Reset(max(delay, minCleanupDelay-time.Since(last), 0))
 }
@@ -3,6 +3,14 @@ package mempot
 import (
 	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -21,6 +29,47 @@ func setupCache(ttlSec, intervalSec int) (*Cache[string, string], context.Cancel
 	return cache, cancel
 }
 
+// fakeClock is a Clock which only advances when told to.
+type fakeClock struct {
+	mut sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mut.Lock()
+	c.now = c.now.Add(d)
+	c.mut.Unlock()
+}
+
+// cleanupInterval is the default CleanupInterval of caches created by setupFakeClockCache.
+const cleanupInterval = time.Millisecond * 10
+
+func setupFakeClockCache(cfg Config) (*Cache[string, string], *fakeClock, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	clock := &fakeClock{now: time.Now()}
+
+	if cfg.CleanupInterval == 0 {
+		cfg.CleanupInterval = cleanupInterval
+	}
+
+	cfg.Clock = clock
+
+	return NewCache[string, string](ctx, cfg), clock, cancel
+}
+
+// waitForCleanup waits until the cleanup goroutine has run at least once.
+func waitForCleanup() {
+	time.Sleep(cleanupInterval * 5)
+}
+
 func TestCacheSetGet(t *testing.T) {
 	cache, cancel := setupCache(1, 1)
 	defer cancel()
@@ -38,23 +87,28 @@ func TestCacheSetGet(t *testing.T) {
 }
 
 func TestCacheExpire(t *testing.T) {
-	cache, cancel := setupCache(1, 1)
+	cache, clock, cancel := setupFakeClockCache(Config{})
 	defer cancel()
 
-	cache.SetWithTTL(key, data, time.Millisecond*50)
+	cache.SetWithTTL(key, data, time.Second)
 
-	time.Sleep(time.Millisecond * 100)
+	clock.Advance(time.Millisecond * 500)
 
 	_, ok := cache.Get(key)
-	if ok {
-		t.Error("item should have expired")
+	if !ok {
+		t.Error("item should not have expired yet")
 	}
 
-	// wait for cleanup
-	time.Sleep(time.Millisecond * 1100)
+	clock.Advance(time.Second)
 
 	_, ok = cache.Get(key)
 	if ok {
+		t.Error("item should have expired")
+	}
+
+	waitForCleanup()
+
+	if n := cache.Len(); n != 0 {
 		t.Error("item still exists after cleanup")
 	}
 }
@@ -86,6 +140,24 @@ func TestCacheReset(t *testing.T) {
 	}
 }
 
+func TestCacheResetWithCapacity(t *testing.T) {
+	cache, cancel := setupCache(1, 1)
+	defer cancel()
+
+	cache.Set(key, data)
+	cache.ResetWithCapacity(100)
+
+	if _, ok := cache.Get(key); ok {
+		t.Error("item still exists after reset")
+	}
+
+	cache.Set(key, data)
+
+	if _, ok := cache.Get(key); !ok {
+		t.Error("item not found after reset")
+	}
+}
+
 func TestCacheRemember(t *testing.T) {
 	cache, cancel := setupCache(1, 1)
 	defer cancel()
@@ -112,16 +184,3585 @@ func TestCacheRemember(t *testing.T) {
 	}
 }
 
+func TestCacheRememberReturnsStoredItem(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// with jitter, every computation of the expiration differs
+	cache := NewCache[string, string](ctx, Config{DefaultTTL: time.Minute, TTLJitter: time.Minute})
+
+	item, err := cache.Remember(key, func(key string) (string, error) {
+		return data, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to remember item: %s", err)
+	}
+
+	stored, _ := cache.Peek(key)
+	if item != stored {
+		t.Errorf("got %+v, want stored item %+v", item, stored)
+	}
+}
+
 func TestCacheNonExpiring(t *testing.T) {
-	cache, cancel := setupCache(1, 1)
+	cache, clock, cancel := setupFakeClockCache(Config{})
 	defer cancel()
 
 	cache.SetWithTTL(key, data, 0)
 
-	time.Sleep(time.Millisecond * 1100)
+	clock.Advance(time.Hour * 24 * 365)
+	waitForCleanup()
 
 	_, ok := cache.Get(key)
 	if !ok {
 		t.Error("non expiring item not found")
 	}
 }
+
+func TestCacheLen(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.Set("a", data)
+	cache.SetWithTTL("b", data, time.Minute)
+	cache.SetWithTTL("c", data, time.Millisecond*50)
+
+	if n := cache.Len(); n != 3 {
+		t.Errorf("got %d, want %d", n, 3)
+	}
+
+	cache.Delete("a")
+
+	if n := cache.Len(); n != 2 {
+		t.Errorf("got %d, want %d", n, 2)
+	}
+
+	clock.Advance(time.Millisecond * 100)
+
+	// expired but not yet cleaned up
+	if n := cache.Len(); n != 2 {
+		t.Errorf("got %d, want %d", n, 2)
+	}
+}
+
+func TestCacheKeys(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{DefaultTTL: time.Second})
+	defer cancel()
+
+	cache.Set("a", data)
+	cache.Set("b", data)
+	cache.SetWithTTL("c", data, time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	keys := cache.Keys()
+	slices.Sort(keys)
+
+	if !slices.Equal(keys, []string{"a", "b"}) {
+		t.Errorf("got %v, want %v", keys, []string{"a", "b"})
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{DefaultTTL: time.Second})
+	defer cancel()
+
+	cache.SetWithTTL(key, data, time.Minute)
+	cache.SetWithTTL("expiring", data, time.Millisecond*50)
+
+	cache.Get(key)
+	cache.Get(key)
+	cache.Get("missing")
+
+	clock.Advance(time.Millisecond * 100)
+
+	cache.Get("expiring")
+
+	if stats := cache.Stats(); stats.Hits != 2 || stats.Misses != 2 {
+		t.Errorf("got %+v, want %d hits and %d misses", stats, 2, 2)
+	}
+
+	waitForCleanup()
+
+	want := Stats{Hits: 2, Misses: 2, Evictions: 1}
+	if stats := cache.Stats(); stats != want {
+		t.Errorf("got %+v, want %+v", stats, want)
+	}
+}
+
+func TestCacheStatsQueries(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	query := func(key string) (string, error) {
+		time.Sleep(time.Millisecond * 10)
+		return data, nil
+	}
+
+	cache.Remember(key, query)
+	cache.Remember(key, query)
+
+	stats := cache.Stats()
+	if stats.Queries != 1 {
+		t.Errorf("got %d queries, want %d", stats.Queries, 1)
+	}
+	if stats.QueryDuration < time.Millisecond*10 {
+		t.Errorf("got query duration %s, want at least %s", stats.QueryDuration, time.Millisecond*10)
+	}
+}
+
+func TestCacheStatsAverageQueryDuration(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	if avg := cache.Stats().AverageQueryDuration(); avg != 0 {
+		t.Errorf("got average query duration %s without queries, want 0", avg)
+	}
+
+	query := func(key string) (string, error) {
+		time.Sleep(time.Millisecond * 20)
+		return data, nil
+	}
+
+	cache.Remember("a", query)
+	cache.Remember("b", query)
+
+	// cache hits do not contribute to the average
+	for range 10 {
+		cache.Remember("a", query)
+	}
+
+	stats := cache.Stats()
+	if stats.Queries != 2 {
+		t.Errorf("got %d queries, want %d", stats.Queries, 2)
+	}
+
+	avg := stats.AverageQueryDuration()
+	if avg < time.Millisecond*20 || avg > time.Millisecond*100 {
+		t.Errorf("got average query duration %s, want between %s and %s", avg, time.Millisecond*20, time.Millisecond*100)
+	}
+}
+
+func TestCacheTTLDistribution(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetWithTTL("a", data, time.Second*30)
+	cache.SetWithTTL("b", data, time.Minute)
+	cache.SetWithTTL("c", data, time.Minute*5)
+	cache.SetWithTTL("d", data, time.Minute*10)
+	cache.SetWithTTL("e", data, time.Hour)
+	cache.SetWithTTL("f", data, 0)
+	cache.SetWithTTL("g", data, time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	got := cache.TTLDistribution([]time.Duration{time.Minute * 10, time.Minute})
+
+	want := map[time.Duration]int{
+		time.Minute:      2,
+		time.Minute * 10: 2,
+		NoExpiration:     1,
+	}
+
+	if !maps.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCacheSetCopier(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[string, []int](ctx, Config{CleanupInterval: time.Hour})
+	cache.SetCopier(slices.Clone[[]int])
+
+	value := []int{1, 2, 3}
+	cache.Set(key, value)
+
+	// modifying the stored value must not affect the cache
+	value[0] = 100
+
+	item, _ := cache.Get(key)
+	if !slices.Equal(item.Data, []int{1, 2, 3}) {
+		t.Errorf("got %v, want %v", item.Data, []int{1, 2, 3})
+	}
+
+	// modifying a returned value must not affect the cache
+	item.Data[1] = 200
+
+	for _, values := range [][]int{cache.Values()[0], cache.GetAll()[key].Data} {
+		values[2] = 300
+	}
+
+	item, _ = cache.Peek(key)
+	if !slices.Equal(item.Data, []int{1, 2, 3}) {
+		t.Errorf("got %v, want %v", item.Data, []int{1, 2, 3})
+	}
+}
+
+func TestCacheSetCopierRemember(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[string, []int](ctx, Config{CleanupInterval: time.Hour})
+	cache.SetCopier(slices.Clone[[]int])
+
+	release := make(chan struct{})
+	results := make(chan []int, 5)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			item, err := cache.Remember(key, func(key string) ([]int, error) {
+				<-release
+				return []int{1, 2, 3}, nil
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			results <- item.Data
+		}()
+	}
+
+	// let all callers wait for the same query
+	time.Sleep(time.Millisecond * 20)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	// modifying the data of one caller must not affect the other callers
+	seen := make(map[*int]bool)
+	for values := range results {
+		if seen[&values[0]] {
+			t.Fatal("callers waiting for the same query share the data")
+		}
+
+		seen[&values[0]] = true
+	}
+}
+
+func TestCacheMemoryUsage(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	overhead := entryOverhead[string, string]()
+
+	cache.SetWithTTL("a", data, time.Minute)
+	cache.SetWithTTL("b", data, time.Minute)
+
+	if usage := cache.MemoryUsage(); usage != 2*overhead {
+		t.Errorf("got %d, want %d", usage, 2*overhead)
+	}
+
+	cache.SetSizer(func(value string) int64 {
+		return 100
+	})
+
+	cache.SetWithTTL("c", data, time.Minute)
+	cache.SetWithTTL("expiring", data, time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	want := 3*100 + 3*overhead
+	if usage := cache.MemoryUsage(); usage != want {
+		t.Errorf("got %d, want %d", usage, want)
+	}
+}
+
+func TestCacheMaxItems(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[string, string](ctx, Config{MaxItems: 2})
+
+	cache.Set("a", data)
+	cache.Set("b", data)
+
+	// mark "a" as recently used so "b" is the least recently used item
+	cache.Get("a")
+
+	cache.Set("c", data)
+
+	if n := cache.Len(); n != 2 {
+		t.Errorf("got %d, want %d", n, 2)
+	}
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("least recently used item still exists")
+	}
+
+	for _, k := range []string{"a", "c"} {
+		if _, ok := cache.Get(k); !ok {
+			t.Errorf("item %s not found", k)
+		}
+	}
+
+	if evictions := cache.Stats().Evictions; evictions != 1 {
+		t.Errorf("got %d evictions, want %d", evictions, 1)
+	}
+}
+
+func TestCacheEvictionPolicyLFU(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[string, string](ctx, Config{MaxItems: 3, EvictionPolicy: LFU})
+
+	cache.Set("a", data)
+	cache.Set("b", data)
+	cache.Set("c", data)
+
+	for range 3 {
+		cache.Get("a")
+	}
+	cache.Get("b")
+	cache.Get("b")
+
+	// "c" is the least frequently used item although "a" is the least recently used one
+	cache.Get("c")
+	cache.Get("a")
+	cache.Get("b")
+
+	cache.Set("d", data)
+
+	if cache.Exists("c") {
+		t.Error("least frequently used item has not been evicted")
+	}
+
+	for _, k := range []string{"a", "b", "d"} {
+		if !cache.Exists(k) {
+			t.Errorf("item %s has been evicted", k)
+		}
+	}
+
+	// "d" has just been added and is not evicted although it has never been accessed
+	cache.Set("e", data)
+
+	if !cache.Exists("e") || cache.Exists("d") {
+		t.Errorf("got keys %v, want %v", cache.Keys(), []string{"a", "b", "e"})
+	}
+}
+
+func TestCacheEvictionPolicyLFUTie(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[string, string](ctx, Config{MaxItems: 3, EvictionPolicy: LFU})
+
+	cache.Set("a", data)
+	cache.Set("b", data)
+	cache.Set("c", data)
+
+	cache.Get("b")
+	cache.Get("a")
+	cache.Get("c")
+
+	cache.Set("d", data)
+
+	if cache.Exists("b") {
+		t.Error("least recently used item has not been evicted on a tie")
+	}
+}
+
+func TestCacheMaxItemsConcurrent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[int, int](ctx, Config{MaxItems: 10})
+
+	var wg sync.WaitGroup
+
+	for i := range 8 {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := range 1000 {
+				cache.Set(i*1000+j, j)
+				cache.Get(i*1000 + j/2)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if n := cache.Len(); n != 10 {
+		t.Errorf("got %d, want %d", n, 10)
+	}
+}
+
+func TestCacheExists(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{DefaultTTL: time.Second})
+	defer cancel()
+
+	cache.Set(key, data)
+	cache.SetWithTTL("expiring", data, time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	if !cache.Exists(key) {
+		t.Error("item should exist")
+	}
+
+	if cache.Exists("missing") {
+		t.Error("missing item should not exist")
+	}
+
+	if cache.Exists("expiring") {
+		t.Error("expired item should not exist")
+	}
+}
+
+func TestCacheExpireAt(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	if cache.ExpireAt(key, clock.Now().Add(time.Hour)) {
+		t.Error("missing item has been changed")
+	}
+
+	cache.SetWithTTL(key, data, time.Minute)
+	cache.SetWithTTL("past", data, time.Minute)
+
+	at := clock.Now().Add(time.Hour)
+
+	if !cache.ExpireAt(key, at) {
+		t.Error("live item has not been changed")
+	}
+
+	if _, expiry, _ := cache.GetWithExpiry(key); !expiry.Equal(at) {
+		t.Errorf("got %s, want %s", expiry, at)
+	}
+
+	if !cache.ExpireAt("past", clock.Now().Add(-time.Second)) {
+		t.Error("live item has not been changed")
+	}
+
+	if cache.Exists("past") {
+		t.Error("item with expiration in the past is still live")
+	}
+
+	clock.Advance(time.Minute * 2)
+	cache.Cleanup()
+
+	if !cache.Exists(key) {
+		t.Error("item expired at its original expiration")
+	}
+
+	if cache.ExpireAt("past", clock.Now().Add(time.Hour)) {
+		t.Error("expired item has been changed")
+	}
+}
+
+func TestCachePersist(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{})
+	defer cancel()
+
+	if cache.Persist(key) {
+		t.Error("missing item has been persisted")
+	}
+
+	cache.SetWithTTL(key, data, time.Millisecond*50)
+	cache.SetWithTTL("expiring", data, time.Millisecond*50)
+
+	if !cache.Persist(key) {
+		t.Error("live item has not been persisted")
+	}
+
+	clock.Advance(time.Hour)
+	waitForCleanup()
+
+	if !cache.Exists(key) {
+		t.Error("persisted item has been expired")
+	}
+
+	if ttl, _ := cache.GetTTL(key); ttl != NoExpiration {
+		t.Errorf("got %s, want %s", ttl, NoExpiration)
+	}
+
+	if cache.Persist("expiring") {
+		t.Error("expired item has been persisted")
+	}
+}
+
+func TestCacheGetOrSet(t *testing.T) {
+	cache, cancel := setupCache(1, 1)
+	defer cancel()
+
+	item, loaded := cache.GetOrSet(key, data)
+	if loaded {
+		t.Error("item should have been stored")
+	}
+
+	if item.Data != data {
+		t.Errorf("got %s, want %s", item.Data, data)
+	}
+
+	item, loaded = cache.GetOrSet(key, "baz")
+	if !loaded {
+		t.Error("item should have been loaded")
+	}
+
+	if item.Data != data {
+		t.Errorf("got %s, want %s", item.Data, data)
+	}
+}
+
+func TestCacheGetOrSetConcurrent(t *testing.T) {
+	cache, cancel := setupCache(1, 1)
+	defer cancel()
+
+	const workers = 50
+
+	var wg sync.WaitGroup
+
+	results := make([]string, workers)
+	stored := atomic.Int32{}
+
+	for i := range workers {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			item, loaded := cache.GetOrSet(key, fmt.Sprintf("value-%d", i))
+			if !loaded {
+				stored.Add(1)
+			}
+
+			results[i] = item.Data
+		}(i)
+	}
+
+	wg.Wait()
+
+	if n := stored.Load(); n != 1 {
+		t.Errorf("value stored %d times, want %d", n, 1)
+	}
+
+	for _, result := range results {
+		if result != results[0] {
+			t.Errorf("got %s, want %s", result, results[0])
+		}
+	}
+}
+
+func TestCacheRememberDeduplicate(t *testing.T) {
+	cache, cancel := setupCache(1, 1)
+	defer cancel()
+
+	const workers = 50
+
+	var (
+		wg      sync.WaitGroup
+		queries atomic.Int32
+		start   = make(chan struct{})
+	)
+
+	errs := make([]error, workers)
+
+	for i := range workers {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			<-start
+
+			_, errs[i] = cache.Remember(key, func(key string) (string, error) {
+				queries.Add(1)
+				time.Sleep(time.Millisecond * 100)
+
+				return "", errors.New("data not available")
+			})
+		}(i)
+	}
+
+	close(start)
+	wg.Wait()
+
+	if n := queries.Load(); n != 1 {
+		t.Errorf("QueryFunc called %d times, want %d", n, 1)
+	}
+
+	for _, err := range errs {
+		if err == nil {
+			t.Error("QueryFunc failed but Remember did not return an error")
+		}
+	}
+}
+
+func TestCacheRememberPerKey(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	// queries for different keys run concurrently, so each query sees the other one start
+	started := map[string]chan struct{}{"a": make(chan struct{}), "b": make(chan struct{})}
+	other := map[string]string{"a": "b", "b": "a"}
+
+	var wg sync.WaitGroup
+
+	for _, k := range []string{"a", "b"} {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := cache.Remember(k, func(key string) (string, error) {
+				close(started[key])
+
+				select {
+				case <-started[other[key]]:
+					return data, nil
+				case <-time.After(time.Second):
+					return "", errors.New("queries for different keys have not run concurrently")
+				}
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// overlapping calls for the same key query only once, even if they start after the query completed
+	var queries atomic.Int32
+
+	for i := range 20 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			time.Sleep(time.Millisecond * time.Duration(i))
+
+			cache.Remember(key, func(key string) (string, error) {
+				queries.Add(1)
+				time.Sleep(time.Millisecond * 5)
+
+				return data, nil
+			})
+		}()
+	}
+
+	wg.Wait()
+
+	if n := queries.Load(); n != 1 {
+		t.Errorf("QueryFunc called %d times, want %d", n, 1)
+	}
+}
+
+func TestCacheRememberOrStale(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{
+		DefaultTTL:      time.Millisecond * 50,
+		GracePeriod:     time.Minute,
+		CleanupInterval: time.Hour,
+	})
+	defer cancel()
+
+	errBackend := errors.New("backend unavailable")
+
+	failing := func(key string) (string, error) {
+		return "", errBackend
+	}
+
+	if _, _, err := cache.RememberOrStale(key, failing); !errors.Is(err, errBackend) {
+		t.Errorf("got error %v without a stale item, want %v", err, errBackend)
+	}
+
+	item, stale, err := cache.RememberOrStale(key, func(key string) (string, error) {
+		return data, nil
+	})
+	if err != nil || stale || item.Data != data {
+		t.Errorf("got %+v, %t and %v, want data %q, not stale and no error", item, stale, err, data)
+	}
+
+	clock.Advance(time.Millisecond * 100)
+
+	item, stale, err = cache.RememberOrStale(key, failing)
+	if err != nil || !stale || item.Data != data {
+		t.Errorf("got %+v, %t and %v, want stale data %q and no error", item, stale, err, data)
+	}
+
+	clock.Advance(time.Minute)
+
+	if _, _, err := cache.RememberOrStale(key, failing); !errors.Is(err, errBackend) {
+		t.Errorf("got error %v after the grace period, want %v", err, errBackend)
+	}
+}
+
+func TestCacheRememberOrStaleCopier(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := &fakeClock{now: time.Now()}
+	cache := NewCache[string, []int](ctx, Config{
+		DefaultTTL:      time.Millisecond * 50,
+		GracePeriod:     time.Minute,
+		CleanupInterval: time.Hour,
+		Clock:           clock,
+	})
+	cache.SetCopier(slices.Clone[[]int])
+	cache.Set(key, []int{1, 2, 3})
+
+	clock.Advance(time.Millisecond * 100)
+
+	failing := func(key string) ([]int, error) {
+		return nil, errors.New("backend unavailable")
+	}
+
+	// modifying a stale value must not affect the cache
+	item, stale, _ := cache.RememberOrStale(key, failing)
+	if !stale {
+		t.Fatal("got no stale item")
+	}
+
+	item.Data[0] = 100
+
+	item, _, _ = cache.RememberOrStale(key, failing)
+	if !slices.Equal(item.Data, []int{1, 2, 3}) {
+		t.Errorf("got %v, want %v", item.Data, []int{1, 2, 3})
+	}
+}
+
+func TestCacheZeroValuePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy ZeroValuePolicy
+		ttl    time.Duration
+
+		// queries is the number of QueryFunc calls for three Remember calls,
+		// the last one after 100ms
+		queries int32
+	}{
+		{name: "cache", policy: CacheZeroValues, queries: 1},
+		{name: "skip", policy: SkipZeroValues, queries: 3},
+		{name: "short ttl", policy: ShortTTLZeroValues, ttl: time.Millisecond * 50, queries: 2},
+		{name: "short ttl without ttl", policy: ShortTTLZeroValues, queries: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, clock, cancel := setupFakeClockCache(Config{
+				DefaultTTL:      time.Minute,
+				CleanupInterval: time.Hour,
+				ZeroValuePolicy: tt.policy,
+				ZeroValueTTL:    tt.ttl,
+			})
+			defer cancel()
+
+			var queries atomic.Int32
+
+			query := func(key string) (string, error) {
+				queries.Add(1)
+				return "", nil
+			}
+
+			for i := range 3 {
+				if i == 2 {
+					clock.Advance(time.Millisecond * 100)
+				}
+
+				item, err := cache.Remember(key, query)
+				if err != nil || item.Data != "" {
+					t.Errorf("got %+v and %v, want the zero value and no error", item, err)
+				}
+			}
+
+			if n := queries.Load(); n != tt.queries {
+				t.Errorf("QueryFunc called %d times, want %d", n, tt.queries)
+			}
+
+			// non-zero values are always cached with the regular time-to-live
+			cache.Remember("other", func(key string) (string, error) {
+				return data, nil
+			})
+
+			if ttl, _ := cache.GetTTL("other"); ttl != time.Minute {
+				t.Errorf("got ttl %s for a non-zero value, want %s", ttl, time.Minute)
+			}
+		})
+	}
+}
+
+func TestCacheWarmUp(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	errBackend := errors.New("backend unavailable")
+
+	var running, peak atomic.Int64
+
+	query := func(key string) (string, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond * 5)
+
+		if strings.HasPrefix(key, "bad") {
+			return "", errBackend
+		}
+
+		return "value-" + key, nil
+	}
+
+	keys := []string{"a", "b", "c", "d", "e", "f", "bad-1", "bad-2"}
+
+	err := cache.WarmUp(keys, query, 3)
+	if !errors.Is(err, errBackend) {
+		t.Errorf("got %v, want %v", err, errBackend)
+	}
+
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 2 {
+		t.Errorf("got %d errors, want %d", n, 2)
+	}
+
+	if p := peak.Load(); p > 3 {
+		t.Errorf("got %d concurrent queries, want at most %d", p, 3)
+	}
+
+	for _, k := range keys[:6] {
+		if item, ok := cache.Get(k); !ok || item.Data != "value-"+k {
+			t.Errorf("got %+v for %s, want %s", item, k, "value-"+k)
+		}
+	}
+
+	if n := cache.Len(); n != 6 {
+		t.Errorf("got %d items, want %d", n, 6)
+	}
+}
+
+func TestCacheWarmUpCanceled(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	cancel()
+
+	err := cache.WarmUp([]string{"a", "b"}, func(key string) (string, error) {
+		return data, nil
+	}, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCacheLoadOrStore(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	var queries atomic.Int64
+
+	query := func(key string) (string, error) {
+		queries.Add(1)
+		time.Sleep(time.Millisecond * 10)
+
+		return data, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			item, err := cache.LoadOrStore(key, query)
+			if err != nil {
+				t.Errorf("failed to load item: %s", err)
+			}
+
+			if item.Data != data {
+				t.Errorf("got %s, want %s", item.Data, data)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := queries.Load(); n != 1 {
+		t.Errorf("got %d queries, want %d", n, 1)
+	}
+
+	errBackend := errors.New("backend unavailable")
+
+	_, err := cache.LoadOrStore("failing", func(key string) (string, error) {
+		return "", errBackend
+	})
+	if !errors.Is(err, errBackend) {
+		t.Errorf("got %v, want %v", err, errBackend)
+	}
+
+	if cache.Exists("failing") {
+		t.Error("failed result has been stored")
+	}
+}
+
+func TestCacheLoadOrStorePanic(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	func() {
+		defer func() {
+			if r := recover(); r != "query failed" {
+				t.Errorf("got %v, want %q", r, "query failed")
+			}
+		}()
+
+		_, _ = cache.LoadOrStore(key, func(key string) (string, error) {
+			panic("query failed")
+		})
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		cache.Set(key, data)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("lock is still held after a panic in the query")
+	}
+}
+
+func TestCacheRememberContext(t *testing.T) {
+	cache, cancel := setupCache(1, 1)
+	defer cancel()
+
+	item, err := cache.RememberContext(context.Background(), key, func(ctx context.Context, key string) (string, error) {
+		return data, nil
+	})
+	if err != nil {
+		t.Errorf("failed to remember item: %s", err)
+	}
+
+	if item.Data != data {
+		t.Errorf("got %s, want %s", item.Data, data)
+	}
+}
+
+func TestCacheRememberContextCanceled(t *testing.T) {
+	cache, cancel := setupCache(1, 1)
+	defer cancel()
+
+	ctx, cancelQuery := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancelQuery()
+
+	queryErr := make(chan error, 1)
+
+	_, err := cache.RememberContext(ctx, key, func(ctx context.Context, key string) (string, error) {
+		time.Sleep(time.Millisecond * 100)
+		queryErr <- ctx.Err()
+
+		return data, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// the query is shared with other callers, so it is not canceled with the context of the first caller
+	if err = <-queryErr; err != nil {
+		t.Errorf("query got %v, want no error", err)
+	}
+
+	ctx, cancelQuery = context.WithCancel(context.Background())
+	cancelQuery()
+
+	_, err = cache.RememberContext(ctx, "other", func(ctx context.Context, key string) (string, error) {
+		t.Error("QueryContextFunc called with canceled context")
+		return data, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCacheRememberContextSharedQuery(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	query := func(ctx context.Context, key string) (string, error) {
+		close(started)
+		<-release
+
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
+		return data, nil
+	}
+
+	ctxA, cancelA := context.WithCancel(context.Background())
+	errA := make(chan error, 1)
+
+	go func() {
+		_, err := cache.RememberContext(ctxA, key, query)
+		errA <- err
+	}()
+
+	<-started
+
+	resultB := make(chan error, 1)
+
+	go func() {
+		item, err := cache.RememberContext(context.Background(), key, query)
+		if err == nil && item.Data != data {
+			err = fmt.Errorf("got %q, want %q", item.Data, data)
+		}
+
+		resultB <- err
+	}()
+
+	cancelA()
+
+	if err := <-errA; !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v for the canceled caller, want %v", err, context.Canceled)
+	}
+
+	close(release)
+
+	if err := <-resultB; err != nil {
+		t.Errorf("got %v for the other caller, want no error", err)
+	}
+}
+
+func TestCacheRememberPanic(t *testing.T) {
+	for _, timeout := range []time.Duration{0, time.Second} {
+		t.Run(fmt.Sprintf("timeout=%s", timeout), func(t *testing.T) {
+			cache, _, cancel := setupFakeClockCache(Config{QueryTimeout: timeout})
+			defer cancel()
+
+			func() {
+				defer func() {
+					if r := recover(); r != "query failed" {
+						t.Errorf("got panic %v, want %q", r, "query failed")
+					}
+				}()
+
+				cache.Remember(key, func(key string) (string, error) {
+					panic("query failed")
+				})
+			}()
+
+			// the call has been completed, so the key can be queried again
+			item, err := cache.Remember(key, func(key string) (string, error) {
+				return data, nil
+			})
+			if err != nil || item.Data != data {
+				t.Errorf("got %+v and %v, want data %q and no error", item, err, data)
+			}
+		})
+	}
+}
+
+func TestCacheGetOrDefault(t *testing.T) {
+	cache, cancel := setupCache(1, 1)
+	defer cancel()
+
+	if value := cache.GetOrDefault(key, "fallback"); value != "fallback" {
+		t.Errorf("got %q, want %q", value, "fallback")
+	}
+
+	if cache.Exists(key) {
+		t.Error("fallback has been stored in the cache")
+	}
+
+	cache.Set(key, data)
+
+	if value := cache.GetOrDefault(key, "fallback"); value != data {
+		t.Errorf("got %q, want %q", value, data)
+	}
+}
+
+func TestCacheTryGet(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	cache.Set(key, data)
+
+	item, ok, err := cache.TryGet(key, time.Millisecond*10)
+	if err != nil || !ok || item.Data != data {
+		t.Errorf("got %+v, %t and %v, want data %q, true and no error", item, ok, err, data)
+	}
+
+	// simulate a long running write
+	cache.mut.Lock()
+
+	start := time.Now()
+	_, ok, err = cache.TryGet(key, time.Millisecond*20)
+
+	if !errors.Is(err, ErrLockTimeout) || ok {
+		t.Errorf("got %t and %v, want false and %v", ok, err, ErrLockTimeout)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Millisecond*20 {
+		t.Errorf("TryGet returned after %s, want at least %s", elapsed, time.Millisecond*20)
+	}
+
+	time.AfterFunc(time.Millisecond*10, cache.mut.Unlock)
+
+	if _, ok, err := cache.TryGet(key, time.Second); err != nil || !ok {
+		t.Errorf("got %t and %v after the lock has been released, want true and no error", ok, err)
+	}
+}
+
+func TestCacheTryGetLazyExpiration(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{LazyExpiration: true})
+	defer cancel()
+
+	// the cleanup goroutine would wait for the write lock as well
+	cache.SetCleanupInterval(0)
+
+	cache.SetWithTTL(key, data, time.Millisecond*50)
+	clock.Advance(time.Millisecond * 100)
+
+	// simulate a long running read, which blocks the removal of the expired Item
+	cache.mut.RLock()
+	defer cache.mut.RUnlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		if _, ok, err := cache.TryGet(key, time.Millisecond*20); err != nil || ok {
+			t.Errorf("got %t and %v, want false and no error", ok, err)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("TryGet waited for the write lock to remove the expired item")
+	}
+}
+
+func TestCacheGetErr(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetWithTTL(key, data, time.Minute)
+	cache.SetWithTTL("expiring", data, time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	value, err := cache.GetErr(key)
+	if err != nil {
+		t.Errorf("failed to get item: %s", err)
+	}
+
+	if value != data {
+		t.Errorf("got %s, want %s", value, data)
+	}
+
+	for _, k := range []string{"missing", "expiring"} {
+		if _, err := cache.GetErr(k); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v for %s, want %v", err, k, ErrNotFound)
+		}
+	}
+}
+
+func TestCacheGetStale(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetWithTTL(key, data, time.Minute)
+	cache.SetWithTTL("expiring", data, time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	item, found, stale := cache.GetStale(key)
+	if !found || stale || item.Data != data {
+		t.Errorf("got %+v, found %t and stale %t, want live item", item, found, stale)
+	}
+
+	item, found, stale = cache.GetStale("expiring")
+	if !found || !stale || item.Data != data {
+		t.Errorf("got %+v, found %t and stale %t, want stale item", item, found, stale)
+	}
+
+	if _, found, _ := cache.GetStale("missing"); found {
+		t.Error("missing item found")
+	}
+
+	cache.Cleanup()
+
+	if _, found, _ := cache.GetStale("expiring"); found {
+		t.Error("removed item found")
+	}
+}
+
+func TestCacheGetOrdered(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetWithTTL("a", "1", time.Minute)
+	cache.SetWithTTL("b", "2", time.Millisecond*50)
+	cache.SetWithTTL("c", "3", time.Minute)
+
+	clock.Advance(time.Millisecond * 100)
+
+	results := cache.GetOrdered([]string{"c", "missing", "a", "b", "c"})
+
+	want := []struct {
+		key  string
+		data string
+		ok   bool
+	}{
+		{key: "c", data: "3", ok: true},
+		{key: "missing"},
+		{key: "a", data: "1", ok: true},
+		{key: "b"},
+		{key: "c", data: "3", ok: true},
+	}
+
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+
+	for i, w := range want {
+		r := results[i]
+		if r.Key != w.key || r.Item.Data != w.data || r.OK != w.ok {
+			t.Errorf("result %d: got %+v, want key %q, data %q and ok %t", i, r, w.key, w.data, w.ok)
+		}
+	}
+
+	if stats := cache.Stats(); stats.Hits != 3 || stats.Misses != 2 {
+		t.Errorf("got %+v, want %d hits and %d misses", stats, 3, 2)
+	}
+}
+
+func TestCacheGetIfVersion(t *testing.T) {
+	cache, cancel := setupCache(1, 1)
+	defer cancel()
+
+	cache.SetWithVersion(key, data, "v1")
+
+	item, ok := cache.GetIfVersion(key, "v1")
+	if !ok {
+		t.Fatal("item with matching version not found")
+	}
+
+	if item.Data != data || item.Version != "v1" {
+		t.Errorf("got %+v, want data %q and version %q", item, data, "v1")
+	}
+
+	if _, ok := cache.GetIfVersion(key, "v2"); ok {
+		t.Error("item with mismatching version has been returned")
+	}
+
+	if _, ok := cache.GetIfVersion("missing", "v1"); ok {
+		t.Error("missing item has been returned")
+	}
+
+	cache.Set(key, data)
+
+	if _, ok := cache.GetIfVersion(key, "v1"); ok {
+		t.Error("version has not been reset by Set")
+	}
+}
+
+func TestCacheGetWithExpiry(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{DefaultTTL: time.Second})
+	defer cancel()
+
+	cache.SetWithTTL(key, data, time.Minute)
+	cache.SetWithTTL("expiring", data, time.Millisecond*50)
+	cache.SetWithTTL("persistent", data, 0)
+
+	want := clock.Now().Add(time.Minute)
+
+	value, expiry, ok := cache.GetWithExpiry(key)
+	if !ok {
+		t.Fatal("item not found")
+	}
+
+	if value != data {
+		t.Errorf("got %s, want %s", value, data)
+	}
+
+	if d := expiry.Sub(want); d < -time.Millisecond || d > time.Millisecond {
+		t.Errorf("got %s, want %s", expiry, want)
+	}
+
+	_, expiry, ok = cache.GetWithExpiry("persistent")
+	if !ok {
+		t.Fatal("item not found")
+	}
+
+	if !expiry.IsZero() {
+		t.Errorf("got %s, want zero time", expiry)
+	}
+
+	clock.Advance(time.Millisecond * 100)
+
+	if _, _, ok := cache.GetWithExpiry("expiring"); ok {
+		t.Error("expired item found")
+	}
+}
+
+func TestCacheSetDefaultTTL(t *testing.T) {
+	cache, _, cancel := setupFakeClockCache(Config{DefaultTTL: time.Minute, CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.Set("a", data)
+
+	cache.SetDefaultTTL(time.Hour)
+
+	if ttl := cache.DefaultTTL(); ttl != time.Hour {
+		t.Errorf("got %s, want %s", ttl, time.Hour)
+	}
+
+	cache.Set("b", data)
+
+	if ttl, _ := cache.GetTTL("a"); ttl <= time.Second*59 || ttl > time.Minute {
+		t.Errorf("got %s, want about %s", ttl, time.Minute)
+	}
+
+	if ttl, _ := cache.GetTTL("b"); ttl <= time.Minute*59 || ttl > time.Hour {
+		t.Errorf("got %s, want about %s", ttl, time.Hour)
+	}
+
+	if ttl := cache.Clone().DefaultTTL(); ttl != time.Hour {
+		t.Errorf("got %s for clone, want %s", ttl, time.Hour)
+	}
+}
+
+func TestCacheTrackAccessTime(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour, TrackAccessTime: true})
+	defer cancel()
+
+	created := clock.Now().UnixNano()
+
+	cache.SetWithTTL(key, data, time.Minute)
+
+	item, _ := cache.Peek(key)
+	if item.CreatedAt != created || item.LastAccessedAt != 0 {
+		t.Errorf("got %+v, want created at %d and not accessed", item, created)
+	}
+
+	clock.Advance(time.Second)
+
+	item, _ = cache.Get(key)
+	if item.LastAccessedAt != clock.Now().UnixNano() {
+		t.Errorf("got %d, want %d", item.LastAccessedAt, clock.Now().UnixNano())
+	}
+
+	first := item.LastAccessedAt
+
+	clock.Advance(time.Second)
+
+	item, _ = cache.Get(key)
+	if item.LastAccessedAt <= first {
+		t.Errorf("got %d, want later than %d", item.LastAccessedAt, first)
+	}
+
+	if item.CreatedAt != created {
+		t.Errorf("got %d, want %d", item.CreatedAt, created)
+	}
+
+	cache.Update(key, func(old string) string {
+		return "baz"
+	})
+
+	if item, _ := cache.Peek(key); item.CreatedAt != created {
+		t.Errorf("got %d after update, want %d", item.CreatedAt, created)
+	}
+}
+
+func TestCacheTrackAccessTimeDisabled(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetWithTTL(key, data, time.Minute)
+	clock.Advance(time.Second)
+
+	if item, _ := cache.Get(key); item.LastAccessedAt != 0 {
+		t.Errorf("got %d, want %d", item.LastAccessedAt, 0)
+	}
+}
+
+func TestCacheGetTTL(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{DefaultTTL: time.Second})
+	defer cancel()
+
+	cache.SetWithTTL(key, data, time.Minute)
+	cache.SetWithTTL("expiring", data, time.Millisecond*50)
+	cache.SetWithTTL("persistent", data, 0)
+
+	ttl, ok := cache.GetTTL(key)
+	if !ok {
+		t.Error("item not found")
+	}
+
+	if ttl <= time.Second*59 || ttl > time.Minute {
+		t.Errorf("got %s, want about %s", ttl, time.Minute)
+	}
+
+	ttl, ok = cache.GetTTL("expiring")
+	if !ok {
+		t.Error("item not found")
+	}
+
+	if ttl < 0 || ttl > time.Millisecond*50 {
+		t.Errorf("got %s, want at most %s", ttl, time.Millisecond*50)
+	}
+
+	ttl, ok = cache.GetTTL("persistent")
+	if !ok {
+		t.Error("item not found")
+	}
+
+	if ttl != NoExpiration {
+		t.Errorf("got %s, want %s", ttl, NoExpiration)
+	}
+
+	clock.Advance(time.Millisecond * 100)
+
+	if _, ok = cache.GetTTL("expiring"); ok {
+		t.Error("item should have expired")
+	}
+
+	if _, ok = cache.GetTTL("missing"); ok {
+		t.Error("missing item should not be found")
+	}
+}
+
+func TestCacheTouch(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{DefaultTTL: time.Second})
+	defer cancel()
+
+	cache.SetWithTTL(key, data, time.Millisecond*100)
+	cache.SetWithTTL("expiring", data, time.Millisecond*50)
+
+	if !cache.Touch(key, time.Minute) {
+		t.Error("failed to touch item")
+	}
+
+	clock.Advance(time.Millisecond * 150)
+
+	item, ok := cache.Get(key)
+	if !ok {
+		t.Error("touched item should not have expired")
+	}
+
+	if item.Data != data {
+		t.Errorf("got %s, want %s", item.Data, data)
+	}
+
+	if cache.Touch("expiring", time.Minute) {
+		t.Error("expired item should not be touched")
+	}
+
+	if _, ok = cache.Get("expiring"); ok {
+		t.Error("expired item has been resurrected")
+	}
+
+	if cache.Touch("missing", time.Minute) {
+		t.Error("missing item should not be touched")
+	}
+}
+
+func TestCacheSlidingExpiration(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{SlidingExpiration: true})
+	defer cancel()
+
+	cache.SetWithTTL(key, data, time.Millisecond*100)
+
+	for range 5 {
+		clock.Advance(time.Millisecond * 50)
+
+		if _, ok := cache.Get(key); !ok {
+			t.Fatal("item should not have expired while being accessed")
+		}
+	}
+
+	clock.Advance(time.Millisecond * 150)
+
+	if _, ok := cache.Get(key); ok {
+		t.Error("item should have expired after not being accessed")
+	}
+}
+
+func TestCacheSetBatch(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{DefaultTTL: time.Hour, CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetBatch(map[string]ItemInput[string]{
+		"a": {Value: "1", TTL: time.Minute},
+		"b": {Value: "2", TTL: time.Second},
+		"c": {Value: "3"},
+	})
+
+	want := map[string]int64{
+		"a": clock.Now().Add(time.Minute).UnixNano(),
+		"b": clock.Now().Add(time.Second).UnixNano(),
+		"c": 0,
+	}
+
+	for key, ttl := range want {
+		item, ok := cache.Get(key)
+		if !ok {
+			t.Fatalf("item %q not found", key)
+		}
+
+		if item.TTL != ttl {
+			t.Errorf("item %q: got ttl %d, want %d", key, item.TTL, ttl)
+		}
+	}
+
+	clock.Advance(time.Second * 2)
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("item b should have expired")
+	}
+
+	if _, ok := cache.Get("c"); !ok {
+		t.Error("item c should not expire")
+	}
+}
+
+func TestCacheGetAndDelete(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.Set(key, data)
+
+	item, ok := cache.GetAndDelete(key)
+	if !ok || item.Data != data {
+		t.Errorf("got %+v and %t, want data %q and true", item, ok, data)
+	}
+
+	if _, ok := cache.GetAndDelete(key); ok {
+		t.Error("item has been returned twice")
+	}
+
+	cache.SetWithTTL("expired", data, time.Millisecond*50)
+	clock.Advance(time.Millisecond * 100)
+
+	if _, ok := cache.GetAndDelete("expired"); ok {
+		t.Error("expired item has been returned")
+	}
+
+	if n := cache.Len(); n != 0 {
+		t.Errorf("got %d items, want %d", n, 0)
+	}
+}
+
+func TestCacheGetAndDeleteConcurrent(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	cache.Set(key, data)
+
+	var (
+		wg    sync.WaitGroup
+		taken atomic.Int64
+	)
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if _, ok := cache.GetAndDelete(key); ok {
+				taken.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if n := taken.Load(); n != 1 {
+		t.Errorf("item has been taken %d times, want %d", n, 1)
+	}
+}
+
+func TestCacheDeleteMany(t *testing.T) {
+	cache, cancel := setupCache(1, 1)
+	defer cancel()
+
+	for _, k := range []string{"a", "b", "c", "d"} {
+		cache.Set(k, data)
+	}
+
+	cache.DeleteMany([]string{"a", "c", "missing"})
+
+	keys := cache.Keys()
+	slices.Sort(keys)
+
+	if !slices.Equal(keys, []string{"b", "d"}) {
+		t.Errorf("got %v, want %v", keys, []string{"b", "d"})
+	}
+}
+
+func TestCacheResize(t *testing.T) {
+	cache, _, cancel := setupFakeClockCache(Config{MaxItems: 5, CleanupInterval: time.Hour})
+	defer cancel()
+
+	var evicted []string
+
+	cache.OnEvict(func(key string, value string) {
+		evicted = append(evicted, key)
+	})
+
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		cache.Set(k, data)
+	}
+
+	cache.Get("a")
+	cache.Resize(2)
+
+	keys := cache.Keys()
+	slices.Sort(keys)
+
+	if !slices.Equal(keys, []string{"a", "e"}) {
+		t.Errorf("got %v, want %v", keys, []string{"a", "e"})
+	}
+
+	if !slices.Equal(evicted, []string{"b", "c", "d"}) {
+		t.Errorf("got evicted %v, want %v", evicted, []string{"b", "c", "d"})
+	}
+
+	cache.Resize(4)
+
+	for _, k := range []string{"f", "g", "h"} {
+		cache.Set(k, data)
+	}
+
+	if n := cache.Len(); n != 4 {
+		t.Errorf("got %d items after growing, want %d", n, 4)
+	}
+
+	cache.Resize(0)
+
+	for _, k := range []string{"i", "j"} {
+		cache.Set(k, data)
+	}
+
+	if n := cache.Len(); n != 6 {
+		t.Errorf("got %d items without limit, want %d", n, 6)
+	}
+}
+
+func TestCacheResizeConcurrent(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	for i := range 4 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := range 1000 {
+				k := fmt.Sprintf("%d-%d", i, j)
+				cache.Set(k, data)
+				cache.Get(k)
+			}
+		}()
+	}
+
+	for n := range 100 {
+		cache.Resize(n % 10)
+	}
+
+	wg.Wait()
+
+	cache.Resize(10)
+
+	if n := cache.Len(); n > 10 {
+		t.Errorf("got %d items, want at most %d", n, 10)
+	}
+}
+
+func TestCacheInvalidateBefore(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.Set("a", data)
+	cache.Set("b", data)
+
+	clock.Advance(time.Minute)
+	cutoff := clock.Now()
+	clock.Advance(time.Minute)
+
+	cache.Set("c", data)
+	cache.Set("a", data)
+
+	if n := cache.InvalidateBefore(cutoff); n != 1 {
+		t.Errorf("got %d removed items, want %d", n, 1)
+	}
+
+	keys := cache.Keys()
+	slices.Sort(keys)
+
+	if !slices.Equal(keys, []string{"a", "c"}) {
+		t.Errorf("got %v, want %v", keys, []string{"a", "c"})
+	}
+}
+
+func TestCacheInvalidateBeforePrecision(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	// the Items and the cutoff are within the same millisecond
+	cache.Set("before", data)
+	clock.Advance(time.Microsecond * 100)
+	cutoff := clock.Now()
+	clock.Advance(time.Microsecond * 100)
+	cache.Set("after", data)
+
+	if n := cache.InvalidateBefore(cutoff); n != 1 {
+		t.Errorf("got %d removed items, want %d", n, 1)
+	}
+
+	if _, ok := cache.Get("after"); !ok {
+		t.Error("item set after the cutoff has been removed")
+	}
+}
+
+func TestCacheOnEvict(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{})
+	defer cancel()
+
+	evicted := make(chan string, 1)
+
+	cache.OnEvict(func(key string, value string) {
+		// accessing the cache from within the callback must not deadlock
+		cache.Delete(key)
+
+		evicted <- key + "=" + value
+	})
+
+	cache.SetWithTTL(key, data, time.Millisecond*50)
+	clock.Advance(time.Millisecond * 100)
+
+	select {
+	case got := <-evicted:
+		if want := key + "=" + data; got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	case <-time.After(time.Second * 2):
+		t.Error("OnEvict was not called for expired item")
+	}
+}
+
+func TestCacheOnEvictMaxItems(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[string, string](ctx, Config{MaxItems: 1})
+
+	var evicted []string
+
+	cache.OnEvict(func(key string, value string) {
+		evicted = append(evicted, key)
+	})
+
+	cache.Set("a", data)
+	cache.Set("b", data)
+	cache.Delete("b")
+
+	if !slices.Equal(evicted, []string{"a"}) {
+		t.Errorf("got %v, want %v", evicted, []string{"a"})
+	}
+}
+
+func TestCacheAll(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetWithTTL("a", "1", time.Minute)
+	cache.SetWithTTL("b", "2", time.Minute)
+	cache.SetWithTTL("c", "3", time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	items := make(map[string]string)
+
+	for key, item := range cache.All() {
+		items[key] = item.Data
+
+		// modifying the cache during the iteration must not deadlock
+		cache.Delete(key)
+	}
+
+	want := map[string]string{"a": "1", "b": "2"}
+	if !maps.Equal(items, want) {
+		t.Errorf("got %v, want %v", items, want)
+	}
+
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+
+	calls := 0
+
+	for range cache.All() {
+		calls++
+		break
+	}
+
+	if calls != 1 {
+		t.Errorf("got %d iterations, want %d", calls, 1)
+	}
+}
+
+func TestCacheRange(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetWithTTL("a", data, time.Minute)
+	cache.SetWithTTL("b", data, time.Minute)
+	cache.SetWithTTL("c", data, time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	var visited []string
+
+	cache.Range(func(key string, item Item[string]) bool {
+		visited = append(visited, key)
+		return true
+	})
+
+	slices.Sort(visited)
+
+	if !slices.Equal(visited, []string{"a", "b"}) {
+		t.Errorf("got %v, want %v", visited, []string{"a", "b"})
+	}
+
+	calls := 0
+
+	cache.Range(func(key string, item Item[string]) bool {
+		calls++
+		return false
+	})
+
+	if calls != 1 {
+		t.Errorf("got %d calls, want %d", calls, 1)
+	}
+}
+
+func TestCacheValues(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetWithTTL("a", "1", time.Minute)
+	cache.SetWithTTL("b", "2", time.Minute)
+	cache.SetWithTTL("c", "3", time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	values := cache.Values()
+	slices.Sort(values)
+
+	if !slices.Equal(values, []string{"1", "2"}) {
+		t.Errorf("got %v, want %v", values, []string{"1", "2"})
+	}
+
+	if len(values) != len(cache.Keys()) {
+		t.Errorf("got %d values, want %d", len(values), len(cache.Keys()))
+	}
+}
+
+func TestCacheGetAll(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetWithTTL("a", "1", time.Minute)
+	cache.SetWithTTL("b", "2", 0)
+	cache.SetWithTTL("c", "3", time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	items := cache.GetAll()
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want %d", len(items), 2)
+	}
+
+	if _, ok := items["c"]; ok {
+		t.Error("expired item returned")
+	}
+
+	if items["a"].Data != "1" || items["a"].TTL == 0 {
+		t.Errorf("got %+v, want data %s with expiration", items["a"], "1")
+	}
+
+	if items["b"].Data != "2" || items["b"].TTL != 0 {
+		t.Errorf("got %+v, want data %s without expiration", items["b"], "2")
+	}
+
+	items["a"] = Item[string]{Data: "changed"}
+	delete(items, "b")
+
+	if item, _ := cache.Get("a"); item.Data != "1" {
+		t.Errorf("got %s, want %s", item.Data, "1")
+	}
+
+	if !cache.Exists("b") {
+		t.Error("item has been deleted from cache")
+	}
+}
+
+func TestCacheForEachParallel(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	for i := range 50 {
+		cache.SetWithTTL(fmt.Sprint(i), data, time.Minute)
+	}
+	cache.SetWithTTL("expiring", data, time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	var (
+		mut     sync.Mutex
+		visited = make(map[string]int)
+
+		running, peak atomic.Int64
+	)
+
+	cache.ForEachParallel(4, func(key string, value string) {
+		n := running.Add(1)
+		defer running.Add(-1)
+
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+
+		// the lock is not held, so the cache can be modified
+		cache.Touch(key, time.Minute)
+
+		time.Sleep(time.Millisecond)
+
+		mut.Lock()
+		visited[key]++
+		mut.Unlock()
+	})
+
+	if len(visited) != 50 {
+		t.Errorf("got %d visited items, want %d", len(visited), 50)
+	}
+
+	for key, n := range visited {
+		if n != 1 {
+			t.Errorf("got %d visits for %s, want %d", n, key, 1)
+		}
+	}
+
+	if _, ok := visited["expiring"]; ok {
+		t.Error("expired item has been visited")
+	}
+
+	if p := peak.Load(); p > 4 {
+		t.Errorf("got %d concurrent calls, want at most %d", p, 4)
+	}
+}
+
+func TestCacheDump(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetWithTTL("a", "1", time.Minute)
+	cache.SetWithTTL("b", "2", 0)
+	cache.SetWithTTL("c", "3", time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	want := "a\t59.9s\t1\nb\tno expiration\t2\nc\texpired\t3"
+	if dump := cache.Dump(); dump != want {
+		t.Errorf("got %q, want %q", dump, want)
+	}
+}
+
+func TestCacheShortTTL(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetWithTTL(key, data, time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 50)
+
+	if _, ok := cache.Get(key); !ok {
+		t.Error("item expired before its time-to-live has passed")
+	}
+
+	clock.Advance(time.Nanosecond)
+
+	if _, ok := cache.Get(key); ok {
+		t.Error("item has not expired right after its time-to-live has passed")
+	}
+}
+
+func TestCacheSetWithDeadline(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	deadline := clock.Now().Add(time.Minute)
+
+	cache.SetWithDeadline(key, data, deadline)
+	cache.SetWithDeadline("past", data, clock.Now().Add(-time.Second))
+
+	item, ok := cache.Get(key)
+	if !ok {
+		t.Fatal("item not found")
+	}
+
+	if item.TTL != deadline.UnixNano() {
+		t.Errorf("got %d, want %d", item.TTL, deadline.UnixNano())
+	}
+
+	if _, ok = cache.Get("past"); ok {
+		t.Error("item with past deadline should have expired")
+	}
+
+	clock.Advance(time.Minute + time.Second)
+
+	if _, ok = cache.Get(key); ok {
+		t.Error("item should have expired after deadline")
+	}
+}
+
+func TestCachePeek(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{SlidingExpiration: true, MaxItems: 2})
+	defer cancel()
+
+	cache.SetWithTTL("a", data, time.Millisecond*100)
+	cache.SetWithTTL("b", data, time.Millisecond*100)
+
+	clock.Advance(time.Millisecond * 50)
+
+	item, ok := cache.Peek("a")
+	if !ok {
+		t.Fatal("item not found")
+	}
+
+	if item.Data != data {
+		t.Errorf("got %s, want %s", item.Data, data)
+	}
+
+	// "a" is still the least recently used item
+	cache.SetWithTTL("c", data, time.Millisecond*100)
+
+	if _, ok = cache.Peek("a"); ok {
+		t.Error("peeked item should have been evicted")
+	}
+
+	clock.Advance(time.Millisecond * 60)
+
+	if _, ok = cache.Peek("b"); ok {
+		t.Error("peeked item should have expired")
+	}
+
+	if stats := cache.Stats(); stats.Hits != 0 || stats.Misses != 0 {
+		t.Errorf("got %+v, want no hits and misses", stats)
+	}
+}
+
+func TestCacheMaxBytes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[string, string](ctx, Config{MaxBytes: 10})
+	cache.SetSizer(func(value string) int64 {
+		return int64(len(value))
+	})
+
+	cache.Set("a", "1234")
+	cache.Set("b", "1234")
+
+	if n := cache.Len(); n != 2 {
+		t.Errorf("got %d, want %d", n, 2)
+	}
+
+	// mark "a" as recently used so "b" is the least recently used item
+	cache.Get("a")
+
+	cache.Set("c", "1234")
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("least recently used item still exists")
+	}
+
+	// replacing an item must account for the size of the old value
+	cache.Set("c", "12")
+	cache.Set("d", "1234")
+
+	if n := cache.Len(); n != 3 {
+		t.Errorf("got %d, want %d", n, 3)
+	}
+
+	cache.Set("e", "12345678901")
+
+	if n := cache.Len(); n != 0 {
+		t.Errorf("got %d, want %d", n, 0)
+	}
+
+	if evictions := cache.Stats().Evictions; evictions != 5 {
+		t.Errorf("got %d evictions, want %d", evictions, 5)
+	}
+}
+
+func TestCacheTTLJitter(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[int, string](ctx, Config{
+		DefaultTTL:   time.Minute,
+		TTLJitter:    time.Second * 10,
+		JitterSource: rand.NewPCG(1, 2),
+		Clock:        clock,
+	})
+
+	ttls := make(map[int64]struct{})
+
+	for i := range 100 {
+		cache.Set(i, data)
+
+		item, _ := cache.Get(i)
+		ttls[item.TTL] = struct{}{}
+
+		remaining := time.Unix(0, item.TTL).Sub(clock.Now())
+		if remaining < time.Second*50 || remaining > time.Second*70 {
+			t.Errorf("got %s, want between %s and %s", remaining, time.Second*50, time.Second*70)
+		}
+	}
+
+	if len(ttls) < 50 {
+		t.Errorf("got %d distinct expirations, want at least %d", len(ttls), 50)
+	}
+}
+
+func TestCacheRememberWithNegativeTTL(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	queries := 0
+
+	query := func(key string) (string, error) {
+		queries++
+		return "", ErrNotFound
+	}
+
+	_, err := cache.RememberWithNegativeTTL(key, query, time.Minute, time.Second)
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrCachedNotFound) {
+		t.Errorf("got %v, want %v", err, ErrNotFound)
+	}
+
+	_, err = cache.RememberWithNegativeTTL(key, query, time.Minute, time.Second)
+	if !errors.Is(err, ErrCachedNotFound) {
+		t.Errorf("got %v, want %v", err, ErrCachedNotFound)
+	}
+
+	if queries != 1 {
+		t.Errorf("QueryFunc called %d times, want %d", queries, 1)
+	}
+
+	clock.Advance(time.Second * 2)
+
+	_, err = cache.RememberWithNegativeTTL(key, query, time.Minute, time.Second)
+	if errors.Is(err, ErrCachedNotFound) {
+		t.Error("negative result should have expired")
+	}
+
+	if queries != 2 {
+		t.Errorf("QueryFunc called %d times, want %d", queries, 2)
+	}
+
+	// setting the item replaces the negative result
+	cache.Set(key, data)
+
+	item, err := cache.RememberWithNegativeTTL(key, query, time.Minute, time.Second)
+	if err != nil {
+		t.Errorf("failed to remember item: %s", err)
+	}
+
+	if item.Data != data {
+		t.Errorf("got %s, want %s", item.Data, data)
+	}
+
+	// other errors are not cached
+	_, err = cache.RememberWithNegativeTTL("other", func(key string) (string, error) {
+		return "", errors.New("data not available")
+	}, time.Minute, time.Second)
+	if err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want query error", err)
+	}
+
+	if cache.negativeCached("other") {
+		t.Error("non negative error has been cached")
+	}
+}
+
+func TestCacheRefreshAhead(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{
+		DefaultTTL:      time.Second,
+		CleanupInterval: time.Hour,
+		RefreshAhead:    time.Millisecond * 500,
+	})
+	defer cancel()
+
+	var queries atomic.Int64
+	release := make(chan struct{})
+
+	query := func(key string) (string, error) {
+		if queries.Add(1) > 1 {
+			<-release
+		}
+
+		return data, nil
+	}
+
+	if _, err := cache.Remember(key, query); err != nil {
+		t.Fatalf("failed to remember item: %s", err)
+	}
+
+	cache.Set("plain", data)
+
+	_, before, _ := cache.GetWithExpiry(key)
+
+	// not within the window yet
+	clock.Advance(time.Millisecond * 400)
+	cache.Cleanup()
+
+	if n := queries.Load(); n != 1 {
+		t.Errorf("got %d queries, want %d", n, 1)
+	}
+
+	clock.Advance(time.Millisecond * 200)
+
+	// concurrent refreshes are deduplicated
+	cache.Cleanup()
+	cache.Cleanup()
+
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, after, _ := cache.GetWithExpiry(key)
+		if after.After(before) {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("got expiration %s, want later than %s", after, before)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if n := queries.Load(); n != 2 {
+		t.Errorf("got %d queries, want %d", n, 2)
+	}
+
+	clock.Advance(time.Millisecond * 500)
+	cache.Cleanup()
+
+	if !cache.Exists(key) {
+		t.Error("refreshed item has expired")
+	}
+
+	if cache.Exists("plain") {
+		t.Error("item stored by set has been refreshed")
+	}
+}
+
+func TestCacheRefreshAheadOverwrittenBySet(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{
+		DefaultTTL:      time.Second,
+		CleanupInterval: time.Hour,
+		RefreshAhead:    time.Millisecond * 500,
+	})
+	defer cancel()
+
+	var queries atomic.Int64
+
+	if _, err := cache.Remember(key, func(key string) (string, error) {
+		queries.Add(1)
+		return "queried", nil
+	}); err != nil {
+		t.Fatalf("failed to remember item: %s", err)
+	}
+
+	cache.Set(key, "explicit")
+
+	clock.Advance(time.Millisecond * 600)
+	cache.Cleanup()
+
+	// a refresh would run in the background
+	time.Sleep(time.Millisecond * 20)
+
+	if n := queries.Load(); n != 1 {
+		t.Errorf("got %d queries, want %d", n, 1)
+	}
+
+	if item, _ := cache.Get(key); item.Data != "explicit" {
+		t.Errorf("got %q, want %q", item.Data, "explicit")
+	}
+}
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{GracePeriod: time.Minute, StaleWhileRevalidate: true})
+	defer cancel()
+
+	cache.SetWithTTL(key, data, time.Second)
+
+	clock.Advance(time.Second * 2)
+	waitForCleanup()
+
+	var queries atomic.Int32
+
+	refreshed := make(chan struct{})
+
+	query := func(key string) (string, error) {
+		queries.Add(1)
+		<-refreshed
+
+		return "baz", nil
+	}
+
+	for range 3 {
+		item, err := cache.Remember(key, query)
+		if err != nil {
+			t.Fatalf("failed to remember item: %s", err)
+		}
+
+		if item.Data != data {
+			t.Errorf("got %s, want stale %s", item.Data, data)
+		}
+	}
+
+	close(refreshed)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if item, ok := cache.Get(key); ok {
+			if item.Data != "baz" {
+				t.Errorf("got %s, want %s", item.Data, "baz")
+			}
+
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, ok := cache.Get(key); !ok {
+		t.Error("item has not been refreshed")
+	}
+
+	if n := queries.Load(); n != 1 {
+		t.Errorf("QueryFunc called %d times, want %d", n, 1)
+	}
+
+	// items expired for longer than the grace period are removed
+	clock.Advance(time.Hour)
+	waitForCleanup()
+
+	if n := cache.Len(); n != 0 {
+		t.Errorf("got %d, want %d", n, 0)
+	}
+}
+
+func TestCacheClone(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{MaxItems: 3, CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetWithTTL("a", data, time.Minute)
+	cache.SetWithTTL("b", data, time.Minute)
+	cache.SetWithTTL("c", data, time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	clone := cache.Clone()
+
+	if n := clone.Len(); n != 2 {
+		t.Errorf("got %d, want %d", n, 2)
+	}
+
+	if clone.cfg.MaxItems != 3 {
+		t.Errorf("got %d, want %d", clone.cfg.MaxItems, 3)
+	}
+
+	cache.Set("a", "changed")
+	cache.Delete("b")
+
+	item, ok := clone.Get("a")
+	if !ok || item.Data != data {
+		t.Errorf("got %s, want %s", item.Data, data)
+	}
+
+	if _, ok = clone.Peek("b"); !ok {
+		t.Error("item deleted from original is missing in clone")
+	}
+
+	// "a" has been used most recently, so "b" is evicted first
+	clone.Set("d", data)
+	clone.Set("e", data)
+
+	if _, ok = clone.Peek("b"); ok {
+		t.Error("least recently used item still exists in clone")
+	}
+}
+
+func TestCacheOnExpire(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{MaxItems: 2})
+	defer cancel()
+
+	var (
+		mut     sync.Mutex
+		expired []string
+	)
+
+	cache.OnExpire(func(key string, value string) {
+		mut.Lock()
+		expired = append(expired, key)
+		mut.Unlock()
+	})
+
+	cache.SetWithTTL("a", data, time.Minute)
+	cache.SetWithTTL("b", data, time.Minute)
+	cache.SetWithTTL("c", data, time.Millisecond*50)
+	cache.SetWithTTL("d", data, time.Minute)
+	cache.Delete("d")
+
+	clock.Advance(time.Millisecond * 100)
+	waitForCleanup()
+
+	mut.Lock()
+	defer mut.Unlock()
+
+	if !slices.Equal(expired, []string{"c"}) {
+		t.Errorf("got %v, want %v", expired, []string{"c"})
+	}
+}
+
+func TestCacheOnCleanup(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	var scanned, evicted []int
+
+	cache.OnCleanup(func(s, e int) {
+		scanned = append(scanned, s)
+		evicted = append(evicted, e)
+	})
+
+	cache.SetWithTTL("a", data, time.Millisecond*50)
+	cache.SetWithTTL("b", data, time.Millisecond*50)
+	cache.SetWithTTL("c", data, time.Minute)
+	cache.SetWithTTL("d", data, 0)
+
+	clock.Advance(time.Millisecond * 100)
+	cache.Cleanup()
+	cache.Cleanup()
+
+	if !slices.Equal(scanned, []int{4, 2}) {
+		t.Errorf("got scanned %v, want %v", scanned, []int{4, 2})
+	}
+
+	if !slices.Equal(evicted, []int{2, 0}) {
+		t.Errorf("got evicted %v, want %v", evicted, []int{2, 0})
+	}
+}
+
+func TestCacheOnExpirePanic(t *testing.T) {
+	logger := &captureLogger{}
+
+	cache, clock, cancel := setupFakeClockCache(Config{Logger: logger})
+	defer cancel()
+
+	var evicted atomic.Int64
+
+	cache.OnExpire(func(key string, value string) {
+		panic("callback failed for " + key)
+	})
+	cache.OnEvict(func(key string, value string) {
+		evicted.Add(1)
+	})
+
+	cache.SetWithTTL("a", data, time.Millisecond*50)
+	cache.SetWithTTL("b", data, time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+	waitForCleanup()
+
+	// the cleanup goroutine must still be running
+	cache.SetWithTTL("c", data, time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+	waitForCleanup()
+
+	if n := cache.Len(); n != 0 {
+		t.Errorf("got %d items, want %d", n, 0)
+	}
+
+	if n := evicted.Load(); n != 3 {
+		t.Errorf("got %d OnEvict calls, want %d", n, 3)
+	}
+
+	logger.mut.Lock()
+	defer logger.mut.Unlock()
+
+	if !slices.Contains(logger.lines, "mempot: recovered from panic in OnExpire callback: callback failed for c") {
+		t.Errorf("panic has not been logged, got %q", logger.lines)
+	}
+}
+
+func TestCacheQueryTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[string, string](ctx, Config{QueryTimeout: time.Millisecond * 50})
+
+	done := make(chan struct{})
+
+	_, err := cache.Remember(key, func(key string) (string, error) {
+		defer close(done)
+
+		time.Sleep(time.Millisecond * 100)
+
+		return data, nil
+	})
+	if !errors.Is(err, ErrQueryTimeout) {
+		t.Errorf("got %v, want %v", err, ErrQueryTimeout)
+	}
+
+	<-done
+
+	if _, ok := cache.Get(key); ok {
+		t.Error("result of abandoned query has been stored")
+	}
+
+	item, err := cache.RememberContext(ctx, key, func(ctx context.Context, key string) (string, error) {
+		return data, nil
+	})
+	if err != nil {
+		t.Errorf("failed to remember item: %s", err)
+	}
+
+	if item.Data != data {
+		t.Errorf("got %s, want %s", item.Data, data)
+	}
+}
+
+func TestCacheMaxConcurrentQueries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const limit = 3
+
+	cache := NewCache[int, string](ctx, Config{MaxConcurrentQueries: limit})
+
+	var running, peak atomic.Int64
+
+	query := func(key int) (string, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond * 5)
+
+		return data, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := range 30 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := cache.Remember(i, query); err != nil {
+				t.Errorf("failed to remember item: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if p := peak.Load(); p > limit {
+		t.Errorf("got %d concurrent queries, want at most %d", p, limit)
+	}
+
+	if n := cache.Len(); n != 30 {
+		t.Errorf("got %d items, want %d", n, 30)
+	}
+}
+
+func TestCacheMaxConcurrentQueriesCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[string, string](ctx, Config{MaxConcurrentQueries: 1})
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	go cache.Remember("a", func(key string) (string, error) {
+		close(started)
+		<-release
+		return data, nil
+	})
+
+	<-started
+
+	queryCtx, queryCancel := context.WithTimeout(ctx, time.Millisecond*20)
+	defer queryCancel()
+
+	var queried atomic.Bool
+	_, err := cache.RememberContext(queryCtx, "b", func(ctx context.Context, key string) (string, error) {
+		queried.Store(true)
+		return data, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// the query must not run once a slot is free, as nobody waits for it anymore
+	close(release)
+	time.Sleep(time.Millisecond * 20)
+
+	if queried.Load() {
+		t.Error("query has been called although its caller has been canceled")
+	}
+
+	// the next caller starts a new query
+	item, err := cache.Remember("b", func(key string) (string, error) {
+		return data, nil
+	})
+	if err != nil || item.Data != data {
+		t.Errorf("got %+v and %v, want data %q and no error", item, err, data)
+	}
+}
+
+type captureLogger struct {
+	mut   sync.Mutex
+	lines []string
+}
+
+func (l *captureLogger) Printf(format string, args ...any) {
+	l.mut.Lock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+	l.mut.Unlock()
+}
+
+func TestNewCacheCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cache := NewCache[string, string](ctx, Config{CleanupInterval: cleanupInterval})
+
+	select {
+	case <-cache.cleanupDone:
+	default:
+		t.Error("cleanup goroutine has been started with a canceled context")
+	}
+
+	// the cache is still usable
+	cache.SetWithTTL(key, data, time.Millisecond)
+
+	if _, ok := cache.Peek(key); !ok {
+		t.Error("item not found")
+	}
+
+	time.Sleep(time.Millisecond * 5)
+	cache.Cleanup()
+
+	if n := cache.Len(); n != 0 {
+		t.Errorf("got %d items after Cleanup, want %d", n, 0)
+	}
+
+	cache.Close()
+}
+
+func TestCacheCompact(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour, MaxItems: 10_000})
+	defer cancel()
+
+	for i := range 10_000 {
+		cache.Set(fmt.Sprint(i), fmt.Sprint(i))
+	}
+
+	for i := range 9_990 {
+		cache.Delete(fmt.Sprint(i))
+	}
+
+	cache.SetWithTTL("expired", data, time.Millisecond*50)
+	clock.Advance(time.Millisecond * 100)
+
+	cache.Compact()
+
+	if n := cache.Len(); n != 10 {
+		t.Errorf("got %d items, want %d", n, 10)
+	}
+
+	for i := 9_990; i < 10_000; i++ {
+		if item, ok := cache.Get(fmt.Sprint(i)); !ok || item.Data != fmt.Sprint(i) {
+			t.Errorf("got %+v and %t for key %d, want data %q", item, ok, i, fmt.Sprint(i))
+		}
+	}
+
+	// the order of recently used Items must be preserved
+	cache.Resize(5)
+
+	keys := cache.Keys()
+	slices.Sort(keys)
+
+	if want := []string{"9995", "9996", "9997", "9998", "9999"}; !slices.Equal(keys, want) {
+		t.Errorf("got %v, want %v", keys, want)
+	}
+}
+
+func TestCachePauseCleanup(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{})
+	defer cancel()
+
+	cache.PauseCleanup()
+
+	cache.SetWithTTL(key, data, time.Millisecond*50)
+	clock.Advance(time.Millisecond * 100)
+	waitForCleanup()
+
+	if n := cache.Len(); n != 1 {
+		t.Errorf("got %d items while paused, want %d", n, 1)
+	}
+
+	if n := cache.Stats().Evictions; n != 0 {
+		t.Errorf("got %d evictions while paused, want %d", n, 0)
+	}
+
+	cache.ResumeCleanup()
+	waitForCleanup()
+
+	if n := cache.Len(); n != 0 {
+		t.Errorf("got %d items after resuming, want %d", n, 0)
+	}
+}
+
+func TestCachePauseCleanupFromCallback(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{})
+	defer cancel()
+
+	expired := make(chan struct{})
+	cache.OnExpire(func(key string, value string) {
+		cache.PauseCleanup()
+		cache.ResumeCleanup()
+		close(expired)
+	})
+
+	cache.SetWithTTL(key, data, time.Millisecond*50)
+	clock.Advance(time.Millisecond * 100)
+
+	select {
+	case <-expired:
+	case <-time.After(time.Second):
+		t.Fatal("PauseCleanup has not returned within the callback")
+	}
+}
+
+func TestCacheLogger(t *testing.T) {
+	logger := &captureLogger{}
+
+	cache, clock, cancel := setupFakeClockCache(Config{Logger: logger})
+	defer cancel()
+
+	cache.SetWithTTL("a", data, time.Millisecond*50)
+	cache.SetWithTTL("b", data, time.Millisecond*50)
+	cache.SetWithTTL("c", data, time.Minute)
+
+	clock.Advance(time.Millisecond * 100)
+	waitForCleanup()
+
+	logger.mut.Lock()
+	defer logger.mut.Unlock()
+
+	want := []string{"mempot: cleanup removed 2 expired items"}
+	if !slices.Equal(logger.lines, want) {
+		t.Errorf("got %v, want %v", logger.lines, want)
+	}
+}
+
+func TestCacheCleanupBatchSize(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour, CleanupBatchSize: 3})
+	defer cancel()
+
+	for i := range 10 {
+		cache.SetWithTTL(fmt.Sprint(i), data, time.Millisecond*50)
+	}
+	cache.SetWithTTL(key, data, time.Minute)
+
+	clock.Advance(time.Millisecond * 100)
+	cache.Cleanup()
+
+	if n := cache.Len(); n != 1 {
+		t.Errorf("got %d items, want %d", n, 1)
+	}
+
+	if stats := cache.Stats(); stats.Evictions != 10 {
+		t.Errorf("got %d evictions, want %d", stats.Evictions, 10)
+	}
+}
+
+func TestCacheLazyExpiration(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour, LazyExpiration: true})
+	defer cancel()
+
+	var expired []string
+	cache.OnExpire(func(key string, value string) {
+		expired = append(expired, key)
+	})
+
+	cache.SetWithTTL(key, data, time.Minute)
+	cache.SetWithTTL("expiring", data, time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	if _, ok := cache.Get("expiring"); ok {
+		t.Error("expired item found")
+	}
+
+	if _, ok := cache.data["expiring"]; ok {
+		t.Error("expired item has not been removed")
+	}
+
+	if len(expired) != 1 || expired[0] != "expiring" {
+		t.Errorf("got %v, want %v", expired, []string{"expiring"})
+	}
+
+	if n := cache.Len(); n != 1 {
+		t.Errorf("got %d items, want %d", n, 1)
+	}
+
+	if stats := cache.Stats(); stats.Evictions != 1 {
+		t.Errorf("got %d evictions, want %d", stats.Evictions, 1)
+	}
+}
+
+func TestCacheLazyExpirationMiss(t *testing.T) {
+	cache, _, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour, LazyExpiration: true})
+	defer cancel()
+
+	// a miss for a key without an expired Item must not wait for the write lock
+	cache.mut.RLock()
+	defer cache.mut.RUnlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		if _, ok := cache.Get(key); ok {
+			t.Error("missing item found")
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Get waited for the write lock on a miss")
+	}
+}
+
+func TestCacheLazyExpirationDisabled(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetWithTTL(key, data, time.Millisecond*50)
+	clock.Advance(time.Millisecond * 100)
+
+	cache.Get(key)
+
+	if n := cache.Len(); n != 1 {
+		t.Errorf("got %d items, want %d", n, 1)
+	}
+}
+
+func TestCacheCleanup(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetWithTTL("a", data, time.Minute)
+	cache.SetWithTTL("b", data, time.Millisecond*50)
+	cache.SetWithTTL("c", data, time.Millisecond*50)
+
+	clock.Advance(time.Millisecond * 100)
+
+	if n := cache.Len(); n != 3 {
+		t.Errorf("got %d, want %d", n, 3)
+	}
+
+	cache.Cleanup()
+
+	if n := cache.Len(); n != 1 {
+		t.Errorf("got %d, want %d", n, 1)
+	}
+
+	if evictions := cache.Stats().Evictions; evictions != 2 {
+		t.Errorf("got %d evictions, want %d", evictions, 2)
+	}
+}
+
+func TestCacheClose(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{})
+	defer cancel()
+
+	cache.Close()
+	cache.Close()
+
+	select {
+	case <-cache.cleanupDone:
+	default:
+		t.Error("cleanup goroutine has not stopped")
+	}
+
+	cache.SetWithTTL(key, data, time.Millisecond*50)
+
+	if _, ok := cache.Get(key); !ok {
+		t.Error("item not found after close")
+	}
+
+	clock.Advance(time.Millisecond * 100)
+	waitForCleanup()
+
+	if n := cache.Len(); n != 1 {
+		t.Errorf("expired item has been removed after close")
+	}
+}
+
+func TestCacheCloseAndEvict(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	var evicted []string
+	cache.OnEvict(func(key string, value string) {
+		evicted = append(evicted, key+"="+value)
+	})
+
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+
+	cache.CloseAndEvict()
+
+	select {
+	case <-cache.cleanupDone:
+	default:
+		t.Error("cleanup goroutine has not stopped")
+	}
+
+	slices.Sort(evicted)
+
+	if want := []string{"a=1", "b=2"}; !slices.Equal(evicted, want) {
+		t.Errorf("got %v, want %v", evicted, want)
+	}
+
+	if n := cache.Len(); n != 0 {
+		t.Errorf("got %d items, want %d", n, 0)
+	}
+
+	// the Items have been evicted already
+	cache.CloseAndEvict()
+
+	if len(evicted) != 2 {
+		t.Errorf("got %d evictions after closing again, want %d", len(evicted), 2)
+	}
+}
+
+func TestCacheCleanupNextExpiry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[string, string](ctx, Config{CleanupInterval: time.Hour})
+
+	cache.SetWithTTL("a", data, time.Hour)
+	cache.SetWithTTL("b", data, time.Millisecond*20)
+	time.Sleep(time.Millisecond * 100)
+
+	if n := cache.Len(); n != 1 {
+		t.Errorf("got %d items, want %d", n, 1)
+	}
+
+	if n := cache.Stats().Evictions; n != 1 {
+		t.Errorf("got %d evictions, want %d", n, 1)
+	}
+}
+
+func TestCacheCleanupNextExpiryCoalesced(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[string, string](ctx, Config{CleanupInterval: time.Minute})
+
+	var cleanups atomic.Int64
+	cache.OnCleanup(func(scanned, evicted int) {
+		cleanups.Add(1)
+	})
+
+	// the Items expire one after the other within 100ms
+	for i := range 1000 {
+		cache.SetWithTTL(strconv.Itoa(i), data, time.Millisecond+time.Microsecond*100*time.Duration(i))
+	}
+
+	time.Sleep(time.Millisecond * 300)
+
+	if n := cache.Len(); n != 0 {
+		t.Errorf("got %d items, want %d", n, 0)
+	}
+
+	// a cleanup every minCleanupDelay
+	if n := cleanups.Load(); n > 5 {
+		t.Errorf("got %d cleanups, want at most %d", n, 5)
+	}
+}
+
+func TestCacheSetCleanupInterval(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	// the cleanup goroutine wakes up when the Item expires, which must not happen in real time
+	cache.SetWithTTL("a", data, time.Minute)
+	waitForCleanup()
+	clock.Advance(time.Minute * 2)
+	waitForCleanup()
+
+	if n := cache.Len(); n != 1 {
+		t.Fatalf("got %d items, want %d", n, 1)
+	}
+
+	cache.SetCleanupInterval(cleanupInterval)
+	waitForCleanup()
+
+	if n := cache.Len(); n != 0 {
+		t.Errorf("got %d items, want %d", n, 0)
+	}
+
+	// stop the cleanup goroutine
+	cache.SetCleanupInterval(0)
+
+	select {
+	case <-cache.cleanupDone:
+	default:
+		t.Error("cleanup goroutine has not stopped")
+	}
+
+	cache.SetWithTTL("b", data, time.Millisecond*50)
+	clock.Advance(time.Millisecond * 100)
+	waitForCleanup()
+
+	if n := cache.Len(); n != 1 {
+		t.Errorf("got %d items, want %d", n, 1)
+	}
+
+	// start a new cleanup goroutine
+	cache.SetCleanupInterval(cleanupInterval)
+	waitForCleanup()
+
+	if n := cache.Len(); n != 0 {
+		t.Errorf("got %d items, want %d", n, 0)
+	}
+
+	cache.Close()
+	cache.SetCleanupInterval(cleanupInterval)
+
+	select {
+	case <-cache.cleanupDone:
+	default:
+		t.Error("cleanup goroutine has been started after close")
+	}
+}
+
+func TestCacheSetCleanupIntervalConcurrent(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.SetCleanupInterval(time.Duration(i%3) * time.Millisecond)
+		}()
+	}
+	wg.Wait()
+
+	cache.Close()
+}
+
+func TestCacheRememberIf(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	var queries atomic.Int64
+
+	query := func(key string) (string, error) {
+		queries.Add(1)
+
+		if key == "empty" {
+			return "", nil
+		}
+
+		return data, nil
+	}
+
+	notEmpty := func(value string) bool {
+		return value != ""
+	}
+
+	for range 2 {
+		item, err := cache.RememberIf("empty", query, notEmpty)
+		if err != nil {
+			t.Fatalf("failed to remember item: %s", err)
+		}
+
+		if item.Data != "" {
+			t.Errorf("got %s, want empty data", item.Data)
+		}
+	}
+
+	if cache.Exists("empty") {
+		t.Error("non-cacheable result has been stored")
+	}
+
+	if n := queries.Load(); n != 2 {
+		t.Errorf("got %d queries, want %d", n, 2)
+	}
+
+	for range 2 {
+		if _, err := cache.RememberIf(key, query, notEmpty); err != nil {
+			t.Fatalf("failed to remember item: %s", err)
+		}
+	}
+
+	if item, ok := cache.Get(key); !ok || item.Data != data {
+		t.Errorf("got %+v, want %s", item, data)
+	}
+
+	if n := queries.Load(); n != 3 {
+		t.Errorf("got %d queries, want %d", n, 3)
+	}
+}
+
+func TestCacheRememberMany(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	cache.Set("a", "cached")
+
+	var queried [][]string
+
+	query := func(missing []string) (map[string]string, error) {
+		queried = append(queried, slices.Clone(missing))
+
+		results := make(map[string]string)
+		for _, k := range missing {
+			if k != "unknown" {
+				results[k] = "queried"
+			}
+		}
+
+		return results, nil
+	}
+
+	items, err := cache.RememberMany([]string{"a", "b", "c", "unknown"}, query)
+	if err != nil {
+		t.Fatalf("failed to remember items: %s", err)
+	}
+
+	if len(queried) != 1 || !slices.Equal(queried[0], []string{"b", "c", "unknown"}) {
+		t.Errorf("got queried keys %v, want %v", queried, []string{"b", "c", "unknown"})
+	}
+
+	if len(items) != 3 || items["a"].Data != "cached" || items["b"].Data != "queried" || items["c"].Data != "queried" {
+		t.Errorf("got %+v, want cached a and queried b and c", items)
+	}
+
+	if _, ok := items["unknown"]; ok {
+		t.Error("omitted key is part of the result")
+	}
+
+	// all items are cached now, except the unknown one
+	_, err = cache.RememberMany([]string{"a", "b", "c"}, query)
+	if err != nil {
+		t.Fatalf("failed to remember items: %s", err)
+	}
+
+	if len(queried) != 1 {
+		t.Errorf("got %d queries, want %d", len(queried), 1)
+	}
+
+	errBackend := errors.New("backend unavailable")
+
+	_, err = cache.RememberMany([]string{"d"}, func(missing []string) (map[string]string, error) {
+		return nil, errBackend
+	})
+	if !errors.Is(err, errBackend) {
+		t.Errorf("got %v, want %v", err, errBackend)
+	}
+}
+
+func TestCacheRenewOnRemember(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{DefaultTTL: time.Second, CleanupInterval: time.Hour, RenewOnRemember: true})
+	defer cancel()
+
+	var queries atomic.Int64
+
+	query := func(key string) (string, error) {
+		queries.Add(1)
+		return data, nil
+	}
+
+	for range 5 {
+		item, err := cache.Remember(key, query)
+		if err != nil {
+			t.Fatalf("failed to remember item: %s", err)
+		}
+
+		if want := clock.Now().Add(time.Second).UnixNano(); item.TTL != want {
+			t.Errorf("got %d, want %d", item.TTL, want)
+		}
+
+		clock.Advance(time.Millisecond * 600)
+		cache.Cleanup()
+	}
+
+	if n := queries.Load(); n != 1 {
+		t.Errorf("got %d queries, want %d", n, 1)
+	}
+
+	// Get does not renew the item
+	cache.Get(key)
+	clock.Advance(time.Millisecond * 600)
+
+	if cache.Exists(key) {
+		t.Error("item has been renewed by get")
+	}
+}
+
+func TestCacheRememberWithStatus(t *testing.T) {
+	cache, cancel := setupCache(1, 1)
+	defer cancel()
+
+	query := func(key string) (string, error) {
+		return data, nil
+	}
+
+	_, cached, err := cache.RememberWithStatus(key, func(key string) (string, error) {
+		return "", errors.New("data not available")
+	})
+	if err == nil {
+		t.Error("QueryFunc failed but RememberWithStatus did not return an error")
+	}
+
+	if cached {
+		t.Error("failed query reported as cached")
+	}
+
+	item, cached, err := cache.RememberWithStatus(key, query)
+	if err != nil {
+		t.Errorf("failed to remember item: %s", err)
+	}
+
+	if cached {
+		t.Error("queried item reported as cached")
+	}
+
+	if item.Data != data {
+		t.Errorf("got %s, want %s", item.Data, data)
+	}
+
+	item, cached, err = cache.RememberWithStatus(key, query)
+	if err != nil {
+		t.Errorf("failed to remember item: %s", err)
+	}
+
+	if !cached {
+		t.Error("cached item reported as queried")
+	}
+
+	if item.Data != data {
+		t.Errorf("got %s, want %s", item.Data, data)
+	}
+}
+
+func TestCacheRememberWithStatusOverlapping(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour, LazyExpiration: true})
+	defer cancel()
+
+	// the Item is stored again between the lookup of Remember and the query
+	cache.OnExpire(func(key string, value string) {
+		cache.Set(key, data)
+	})
+
+	cache.SetWithTTL(key, data, time.Millisecond*50)
+	clock.Advance(time.Millisecond * 100)
+
+	item, cached, err := cache.RememberWithStatus(key, func(key string) (string, error) {
+		t.Error("query has been called although the item has been stored")
+		return data, nil
+	})
+	if err != nil || !cached || item.Data != data {
+		t.Errorf("got %+v, %t and %v, want data %q, cached and no error", item, cached, err, data)
+	}
+}
+
+func TestCacheSetIfAbsent(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	if !cache.SetIfAbsent(key, data) {
+		t.Error("absent item has not been stored")
+	}
+
+	if cache.SetIfAbsent(key, "baz") {
+		t.Error("live item has been overwritten")
+	}
+
+	if item, _ := cache.Get(key); item.Data != data {
+		t.Errorf("got %s, want %s", item.Data, data)
+	}
+
+	cache.SetWithTTL("expiring", data, time.Millisecond*50)
+	clock.Advance(time.Millisecond * 100)
+
+	if !cache.SetIfAbsent("expiring", "baz") {
+		t.Error("expired item has not been overwritten")
+	}
+
+	if item, _ := cache.Get("expiring"); item.Data != "baz" {
+		t.Errorf("got %s, want %s", item.Data, "baz")
+	}
+}
+
+func TestCacheSetWithTTLIfAbsent(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	var acquired atomic.Int64
+
+	var wg sync.WaitGroup
+	for i := range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if cache.SetWithTTLIfAbsent("lock", fmt.Sprintf("owner-%d", i), time.Millisecond*50) {
+				acquired.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := acquired.Load(); n != 1 {
+		t.Errorf("lock has been acquired %d times, want %d", n, 1)
+	}
+
+	if cache.SetWithTTLIfAbsent("lock", "owner-2", time.Millisecond*50) {
+		t.Error("held lock has been acquired")
+	}
+
+	clock.Advance(time.Millisecond * 100)
+
+	if !cache.SetWithTTLIfAbsent("lock", "owner-2", time.Minute) {
+		t.Error("expired lock has not been acquired")
+	}
+
+	if ttl, _ := cache.GetTTL("lock"); ttl <= time.Millisecond*50 {
+		t.Errorf("got %s, want about %s", ttl, time.Minute)
+	}
+}
+
+func TestCacheReplace(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	if cache.Replace(key, data) {
+		t.Error("absent item has been replaced")
+	}
+
+	if cache.Exists(key) {
+		t.Error("absent item has been created")
+	}
+
+	cache.SetWithTTL(key, data, time.Millisecond*50)
+
+	if !cache.Replace(key, "baz") {
+		t.Error("live item has not been replaced")
+	}
+
+	clock.Advance(time.Millisecond * 100)
+
+	item, ok := cache.Get(key)
+	if !ok {
+		t.Fatal("time-to-live of replaced item has not been refreshed")
+	}
+
+	if item.Data != "baz" {
+		t.Errorf("got %s, want %s", item.Data, "baz")
+	}
+
+	cache.SetWithTTL("expiring", data, time.Millisecond*50)
+	clock.Advance(time.Millisecond * 100)
+
+	if cache.Replace("expiring", "baz") {
+		t.Error("expired item has been replaced")
+	}
+}
+
+func TestCacheUpdate(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	type counter struct {
+		Name  string
+		Count int
+	}
+
+	c := NewCache[string, counter](context.Background(), Config{DefaultTTL: time.Minute, Clock: clock})
+	defer c.Close()
+
+	c.SetWithTTL(key, counter{Name: key}, time.Minute)
+
+	_, before, _ := c.GetWithExpiry(key)
+
+	var wg sync.WaitGroup
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ok := c.Update(key, func(old counter) counter {
+				old.Count++
+				return old
+			})
+			if !ok {
+				t.Error("item not found")
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, after, ok := c.GetWithExpiry(key)
+	if !ok {
+		t.Fatal("item not found")
+	}
+
+	if value.Count != 100 || value.Name != key {
+		t.Errorf("got %+v, want count %d", value, 100)
+	}
+
+	if !after.Equal(before) {
+		t.Errorf("got expiration %s, want %s", after, before)
+	}
+
+	cache.SetWithTTL("expiring", data, time.Millisecond*50)
+	clock.Advance(time.Millisecond * 100)
+
+	called := false
+	update := func(old string) string {
+		called = true
+		return old
+	}
+
+	if cache.Update("expiring", update) || cache.Update("missing", update) {
+		t.Error("expired or missing item has been updated")
+	}
+
+	if called {
+		t.Error("fn has been called for an expired or missing item")
+	}
+}
+
+func TestCacheUpdatePanic(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	cache.Set(key, data)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "update failed" {
+				t.Errorf("got %v, want %q", r, "update failed")
+			}
+		}()
+
+		cache.Update(key, func(old string) string {
+			panic("update failed")
+		})
+	}()
+
+	if !cache.mut.TryLock() {
+		t.Fatal("lock is still held after a panic in fn")
+	}
+	cache.mut.Unlock()
+}
+
+func BenchmarkCacheSetInitialCapacity(b *testing.B) {
+	const n = 10_000
+
+	for _, capacity := range []int{0, n} {
+		b.Run(fmt.Sprintf("capacity=%d", capacity), func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			b.ReportAllocs()
+
+			for range b.N {
+				cache := NewCache[int, int](ctx, Config{CleanupInterval: time.Hour, InitialCapacity: capacity})
+
+				for i := range n {
+					cache.Set(i, i)
+				}
+
+				cache.Close()
+			}
+		})
+	}
+}
+
+func BenchmarkCacheResetWithCapacity(b *testing.B) {
+	const n = 10_000
+
+	for _, capacity := range []int{0, n} {
+		b.Run(fmt.Sprintf("capacity=%d", capacity), func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			cache := NewCache[int, int](ctx, Config{CleanupInterval: time.Hour})
+			defer cache.Close()
+
+			b.ReportAllocs()
+
+			for range b.N {
+				cache.ResetWithCapacity(capacity)
+
+				for i := range n {
+					cache.Set(i, i)
+				}
+			}
+		})
+	}
+}
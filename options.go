@@ -0,0 +1,172 @@
+package mempot
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Option alters the Config of a Cache created by NewCacheWithOptions.
+type Option func(cfg *Config)
+
+// NewCacheWithOptions creates a new Cache instance with K as key and T as data configured by the given options.
+// Settings which are not altered by an option use the value of DefaultConfig.
+// If the context is canceled, the Cache will stop the cleanup goroutine.
+func NewCacheWithOptions[K comparable, T any](ctx context.Context, opts ...Option) *Cache[K, T] {
+	var cfg Config
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return NewCache[K, T](ctx, cfg)
+}
+
+// WithDefaultTTL sets Config.DefaultTTL.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.DefaultTTL = ttl
+	}
+}
+
+// WithCleanupInterval sets Config.CleanupInterval.
+func WithCleanupInterval(interval time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.CleanupInterval = interval
+	}
+}
+
+// WithCleanupBatchSize sets Config.CleanupBatchSize.
+func WithCleanupBatchSize(size int) Option {
+	return func(cfg *Config) {
+		cfg.CleanupBatchSize = size
+	}
+}
+
+// WithMaxItems sets Config.MaxItems.
+func WithMaxItems(n int) Option {
+	return func(cfg *Config) {
+		cfg.MaxItems = n
+	}
+}
+
+// WithInitialCapacity sets Config.InitialCapacity.
+func WithInitialCapacity(n int) Option {
+	return func(cfg *Config) {
+		cfg.InitialCapacity = n
+	}
+}
+
+// WithMaxBytes sets Config.MaxBytes.
+func WithMaxBytes(n int64) Option {
+	return func(cfg *Config) {
+		cfg.MaxBytes = n
+	}
+}
+
+// WithSlidingExpiration sets Config.SlidingExpiration.
+func WithSlidingExpiration(enabled bool) Option {
+	return func(cfg *Config) {
+		cfg.SlidingExpiration = enabled
+	}
+}
+
+// WithEvictionPolicy sets Config.EvictionPolicy.
+func WithEvictionPolicy(policy EvictionPolicy) Option {
+	return func(cfg *Config) {
+		cfg.EvictionPolicy = policy
+	}
+}
+
+// WithTTLJitter sets Config.TTLJitter and Config.JitterSource.
+func WithTTLJitter(jitter time.Duration, src rand.Source) Option {
+	return func(cfg *Config) {
+		cfg.TTLJitter = jitter
+		cfg.JitterSource = src
+	}
+}
+
+// WithGracePeriod sets Config.GracePeriod.
+func WithGracePeriod(d time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.GracePeriod = d
+	}
+}
+
+// WithStaleWhileRevalidate sets Config.StaleWhileRevalidate.
+func WithStaleWhileRevalidate(enabled bool) Option {
+	return func(cfg *Config) {
+		cfg.StaleWhileRevalidate = enabled
+	}
+}
+
+// WithRefreshAhead sets Config.RefreshAhead.
+func WithRefreshAhead(window time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.RefreshAhead = window
+	}
+}
+
+// WithLazyExpiration sets Config.LazyExpiration.
+func WithLazyExpiration(enabled bool) Option {
+	return func(cfg *Config) {
+		cfg.LazyExpiration = enabled
+	}
+}
+
+// WithTrackAccessTime sets Config.TrackAccessTime.
+func WithTrackAccessTime(enabled bool) Option {
+	return func(cfg *Config) {
+		cfg.TrackAccessTime = enabled
+	}
+}
+
+// WithRenewOnRemember sets Config.RenewOnRemember.
+func WithRenewOnRemember(enabled bool) Option {
+	return func(cfg *Config) {
+		cfg.RenewOnRemember = enabled
+	}
+}
+
+// WithZeroValuePolicy sets Config.ZeroValuePolicy and Config.ZeroValueTTL.
+func WithZeroValuePolicy(policy ZeroValuePolicy, ttl time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.ZeroValuePolicy = policy
+		cfg.ZeroValueTTL = ttl
+	}
+}
+
+// WithEventHistorySize sets Config.EventHistorySize.
+func WithEventHistorySize(size int) Option {
+	return func(cfg *Config) {
+		cfg.EventHistorySize = size
+	}
+}
+
+// WithQueryTimeout sets Config.QueryTimeout.
+func WithQueryTimeout(timeout time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.QueryTimeout = timeout
+	}
+}
+
+// WithMaxConcurrentQueries sets Config.MaxConcurrentQueries.
+func WithMaxConcurrentQueries(n int) Option {
+	return func(cfg *Config) {
+		cfg.MaxConcurrentQueries = n
+	}
+}
+
+// WithClock sets Config.Clock.
+func WithClock(clock Clock) Option {
+	return func(cfg *Config) {
+		cfg.Clock = clock
+	}
+}
+
+// WithLogger sets Config.Logger.
+func WithLogger(logger Logger) Option {
+	return func(cfg *Config) {
+		cfg.Logger = logger
+	}
+}
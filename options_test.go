@@ -0,0 +1,79 @@
+package mempot
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestNewCacheWithOptions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := &fakeClock{now: time.Now()}
+	logger := log.New(io.Discard, "", 0)
+
+	cache := NewCacheWithOptions[string, string](ctx,
+		WithDefaultTTL(time.Second),
+		WithCleanupInterval(time.Minute),
+		WithCleanupBatchSize(100),
+		WithMaxItems(10),
+		WithInitialCapacity(16),
+		WithMaxBytes(1024),
+		WithSlidingExpiration(true),
+		WithEvictionPolicy(LFU),
+		WithTTLJitter(time.Millisecond, nil),
+		WithGracePeriod(time.Hour),
+		WithStaleWhileRevalidate(true),
+		WithRefreshAhead(time.Second),
+		WithLazyExpiration(true),
+		WithTrackAccessTime(true),
+		WithRenewOnRemember(true),
+		WithZeroValuePolicy(ShortTTLZeroValues, time.Millisecond*10),
+		WithEventHistorySize(8),
+		WithQueryTimeout(time.Millisecond*100),
+		WithMaxConcurrentQueries(4),
+		WithClock(clock),
+		WithLogger(logger),
+	)
+
+	want := Config{
+		DefaultTTL:           time.Second,
+		CleanupInterval:      time.Minute,
+		CleanupBatchSize:     100,
+		MaxItems:             10,
+		InitialCapacity:      16,
+		MaxBytes:             1024,
+		SlidingExpiration:    true,
+		EvictionPolicy:       LFU,
+		TTLJitter:            time.Millisecond,
+		GracePeriod:          time.Hour,
+		StaleWhileRevalidate: true,
+		RefreshAhead:         time.Second,
+		LazyExpiration:       true,
+		TrackAccessTime:      true,
+		RenewOnRemember:      true,
+		ZeroValuePolicy:      ShortTTLZeroValues,
+		ZeroValueTTL:         time.Millisecond * 10,
+		EventHistorySize:     8,
+		QueryTimeout:         time.Millisecond * 100,
+		MaxConcurrentQueries: 4,
+		Clock:                clock,
+		Logger:               logger,
+	}
+
+	got := cache.cfg
+	got.JitterSource = nil
+
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	cache = NewCacheWithOptions[string, string](ctx)
+
+	if cache.cfg.DefaultTTL != DefaultConfig.DefaultTTL || cache.cfg.CleanupInterval != DefaultConfig.CleanupInterval {
+		t.Errorf("got %+v, want defaults %+v", cache.cfg, DefaultConfig)
+	}
+}
@@ -0,0 +1,41 @@
+// Package oteltracing creates OpenTelemetry spans for the queries made by the Remember methods of a mempot.Cache.
+package oteltracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mycreepy/mempot"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanName is the name of the spans created for queries.
+const SpanName = "mempot.query"
+
+// Query wraps the QueryContextFunc, so a span is created for every call. As the Remember methods only call
+// QueryContextFunc if the Item is not found in the Cache, a span is only created on a cache miss.
+// The span records the key and the error returned by the QueryContextFunc, if any.
+func Query[K comparable, T any](tracer trace.Tracer, query mempot.QueryContextFunc[K, T]) mempot.QueryContextFunc[K, T] {
+	return func(ctx context.Context, key K) (T, error) {
+		ctx, span := tracer.Start(ctx, SpanName, trace.WithAttributes(
+			attribute.String("cache.key", fmt.Sprint(key)),
+			attribute.Bool("cache.hit", false),
+		))
+		defer span.End()
+
+		data, err := query(ctx, key)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		return data, err
+	}
+}
+
+// Remember works like the RememberContext method of the Cache, but creates a span if QueryContextFunc is called.
+func Remember[K comparable, T any](ctx context.Context, cache *mempot.Cache[K, T], tracer trace.Tracer, key K, query mempot.QueryContextFunc[K, T]) (mempot.Item[T], error) {
+	return cache.RememberContext(ctx, key, Query(tracer, query))
+}
@@ -0,0 +1,92 @@
+package oteltracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mycreepy/mempot"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRemember(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := mempot.NewCache[string, string](ctx, mempot.Config{DefaultTTL: time.Minute})
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	query := func(ctx context.Context, key string) (string, error) {
+		return "bar", nil
+	}
+
+	for range 3 {
+		item, err := Remember(ctx, cache, tracer, "foo", query)
+		if err != nil {
+			t.Fatalf("failed to remember item: %s", err)
+		}
+		if item.Data != "bar" {
+			t.Errorf("got %s, want %s", item.Data, "bar")
+		}
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want %d", len(spans), 1)
+	}
+
+	if spans[0].Name() != SpanName {
+		t.Errorf("got span name %s, want %s", spans[0].Name(), SpanName)
+	}
+
+	want := []attribute.KeyValue{attribute.String("cache.key", "foo"), attribute.Bool("cache.hit", false)}
+	for _, attr := range want {
+		found := false
+		for _, got := range spans[0].Attributes() {
+			if got == attr {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("attribute %s=%s not found", attr.Key, attr.Value.Emit())
+		}
+	}
+}
+
+func TestRememberError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := mempot.NewCache[string, string](ctx, mempot.Config{DefaultTTL: time.Minute})
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	queryErr := errors.New("backend unavailable")
+
+	_, err := Remember(ctx, cache, tracer, "foo", func(ctx context.Context, key string) (string, error) {
+		return "", queryErr
+	})
+	if !errors.Is(err, queryErr) {
+		t.Fatalf("got %v, want %v", err, queryErr)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want %d", len(spans), 1)
+	}
+
+	if status := spans[0].Status(); status.Code != codes.Error || status.Description != queryErr.Error() {
+		t.Errorf("got status %+v, want %s with description %s", status, codes.Error, queryErr)
+	}
+
+	if events := spans[0].Events(); len(events) != 1 {
+		t.Errorf("got %d events, want %d", len(events), 1)
+	}
+}
@@ -0,0 +1,90 @@
+// Package prommetrics exports the statistics of a mempot.Cache as Prometheus metrics.
+package prommetrics
+
+import (
+	"github.com/mycreepy/mempot"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Source provides the statistics of a cache, e.g. a mempot.Cache or a mempot.ShardedCache.
+type Source interface {
+	// Stats returns the current statistics of the cache.
+	Stats() mempot.Stats
+
+	// Len returns the number of items in the cache.
+	Len() int
+}
+
+// Opts allows to alter the names and labels of the exported metrics.
+type Opts struct {
+	// Namespace is the namespace of the metric names.
+	//
+	// Default: mempot
+	Namespace string
+
+	// Subsystem is the subsystem of the metric names.
+	Subsystem string
+
+	// ConstLabels are added to every metric, e.g. to distinguish multiple caches.
+	ConstLabels prometheus.Labels
+}
+
+// Collector is a prometheus.Collector which exports the statistics of a cache on every scrape.
+type Collector struct {
+	src Source
+
+	hits          *prometheus.Desc
+	misses        *prometheus.Desc
+	evictions     *prometheus.Desc
+	items         *prometheus.Desc
+	queries       *prometheus.Desc
+	queryDuration *prometheus.Desc
+}
+
+// NewCollector creates a new Collector for the statistics of the given cache.
+func NewCollector(src Source, opts Opts) *Collector {
+	if opts.Namespace == "" {
+		opts.Namespace = "mempot"
+	}
+
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(opts.Namespace, opts.Subsystem, name), help, nil, opts.ConstLabels)
+	}
+
+	return &Collector{
+		src:           src,
+		hits:          desc("hits_total", "Number of cache lookups which returned an item."),
+		misses:        desc("misses_total", "Number of cache lookups which did not return an item."),
+		evictions:     desc("evictions_total", "Number of items removed because they expired or the cache was full."),
+		items:         desc("items", "Number of items in the cache."),
+		queries:       desc("queries_total", "Number of queries made to retrieve data from source."),
+		queryDuration: desc("query_duration_seconds_total", "Total time spent in queries to retrieve data from source."),
+	}
+}
+
+// Register creates a new Collector for the statistics of the given cache and registers it.
+func Register(reg prometheus.Registerer, src Source, opts Opts) error {
+	return reg.Register(NewCollector(src, opts))
+}
+
+// Describe sends the descriptors of all metrics to the channel.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.evictions
+	ch <- c.items
+	ch <- c.queries
+	ch <- c.queryDuration
+}
+
+// Collect sends the current values of all metrics to the channel.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.src.Stats()
+
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.Evictions))
+	ch <- prometheus.MustNewConstMetric(c.items, prometheus.GaugeValue, float64(c.src.Len()))
+	ch <- prometheus.MustNewConstMetric(c.queries, prometheus.CounterValue, float64(stats.Queries))
+	ch <- prometheus.MustNewConstMetric(c.queryDuration, prometheus.CounterValue, stats.QueryDuration.Seconds())
+}
@@ -0,0 +1,88 @@
+package prommetrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mycreepy/mempot"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRegister(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := mempot.NewCache[string, string](ctx, mempot.Config{MaxItems: 1})
+
+	reg := prometheus.NewPedanticRegistry()
+
+	err := Register(reg, cache, Opts{Subsystem: "sessions", ConstLabels: prometheus.Labels{"cache": "test"}})
+	if err != nil {
+		t.Fatalf("failed to register collector: %s", err)
+	}
+
+	cache.Set("a", "bar")
+	cache.Set("b", "bar")
+	cache.Get("b")
+	cache.Get("a")
+
+	_, err = cache.Remember("c", func(key string) (string, error) {
+		time.Sleep(time.Millisecond * 10)
+		return "bar", nil
+	})
+	if err != nil {
+		t.Fatalf("failed to remember item: %s", err)
+	}
+
+	expected := `
+# HELP mempot_sessions_evictions_total Number of items removed because they expired or the cache was full.
+# TYPE mempot_sessions_evictions_total counter
+mempot_sessions_evictions_total{cache="test"} 2
+# HELP mempot_sessions_hits_total Number of cache lookups which returned an item.
+# TYPE mempot_sessions_hits_total counter
+mempot_sessions_hits_total{cache="test"} 1
+# HELP mempot_sessions_items Number of items in the cache.
+# TYPE mempot_sessions_items gauge
+mempot_sessions_items{cache="test"} 1
+# HELP mempot_sessions_misses_total Number of cache lookups which did not return an item.
+# TYPE mempot_sessions_misses_total counter
+mempot_sessions_misses_total{cache="test"} 2
+# HELP mempot_sessions_queries_total Number of queries made to retrieve data from source.
+# TYPE mempot_sessions_queries_total counter
+mempot_sessions_queries_total{cache="test"} 1
+`
+
+	err = testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"mempot_sessions_evictions_total",
+		"mempot_sessions_hits_total",
+		"mempot_sessions_items",
+		"mempot_sessions_misses_total",
+		"mempot_sessions_queries_total",
+	)
+	if err != nil {
+		t.Error(err)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %s", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != "mempot_sessions_query_duration_seconds_total" {
+			continue
+		}
+
+		duration := family.GetMetric()[0].GetCounter().GetValue()
+		if duration < 0.01 {
+			t.Errorf("got %f, want at least %f", duration, 0.01)
+		}
+
+		return
+	}
+
+	t.Error("query duration metric not found")
+}
@@ -0,0 +1,217 @@
+package mempot
+
+import (
+	"context"
+	"math"
+	"math/bits"
+	"reflect"
+	"time"
+)
+
+// ShardedCache distributes its Items across multiple Caches based on the hash of the key to reduce
+// lock contention under heavy concurrent access. Each shard has its own lock and cleanup goroutine.
+type ShardedCache[K comparable, T any] struct {
+	shards []*Cache[K, T]
+	mask   uint64
+}
+
+// NewShardedCache creates a new ShardedCache instance with K as key and T as data.
+// The number of shards is taken from Config.Shards, all other settings apply to each shard individually,
+// e.g. MaxItems limits the number of Items per shard.
+// If the context is canceled, the shards will stop their cleanup goroutines.
+func NewShardedCache[K comparable, T any](ctx context.Context, cfg Config) *ShardedCache[K, T] {
+	n := 1
+	if cfg.Shards > 1 {
+		n = 1 << bits.Len(uint(cfg.Shards-1))
+	}
+
+	s := &ShardedCache[K, T]{
+		shards: make([]*Cache[K, T], n),
+		mask:   uint64(n - 1),
+	}
+
+	for i := range s.shards {
+		s.shards[i] = NewCache[K, T](ctx, cfg)
+	}
+
+	return s
+}
+
+// shard returns the Cache responsible for the key.
+func (s *ShardedCache[K, T]) shard(key K) *Cache[K, T] {
+	if s.mask == 0 {
+		return s.shards[0]
+	}
+
+	return s.shards[hashKey(key)&s.mask]
+}
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// hashKey returns the FNV-1a hash of the key. Strings and integers are hashed directly,
+// all other types by their value using reflection without formatting them.
+func hashKey[K comparable](key K) uint64 {
+	switch k := any(key).(type) {
+	case string:
+		return hashString(fnvOffset64, k)
+	case int:
+		return hashUint64(fnvOffset64, uint64(k))
+	case int64:
+		return hashUint64(fnvOffset64, uint64(k))
+	case uint64:
+		return hashUint64(fnvOffset64, k)
+	}
+
+	return hashValue(fnvOffset64, reflect.ValueOf(key))
+}
+
+// hashValue adds the value to the hash h. Keys which are equal according to == must have the same hash,
+// so pointers are hashed by their address, as they are compared by identity, and negative zero like zero.
+func hashValue(h uint64, v reflect.Value) uint64 {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.UnsafePointer, reflect.Chan:
+		return hashUint64(h, uint64(v.Pointer()))
+	case reflect.String:
+		return hashString(h, v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return hashUint64(h, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return hashUint64(h, v.Uint())
+	case reflect.Bool:
+		if v.Bool() {
+			return hashUint64(h, 1)
+		}
+
+		return hashUint64(h, 0)
+	case reflect.Float32, reflect.Float64:
+		return hashFloat(h, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return hashFloat(hashFloat(h, real(c)), imag(c))
+	case reflect.Interface:
+		return hashValue(h, v.Elem())
+	case reflect.Array:
+		for i := range v.Len() {
+			h = hashValue(h, v.Index(i))
+		}
+
+		return h
+	case reflect.Struct:
+		for i := range v.NumField() {
+			h = hashValue(h, v.Field(i))
+		}
+
+		return h
+	}
+
+	// a nil interface, other kinds are not comparable and cannot be used as keys
+	return h
+}
+
+func hashFloat(h uint64, f float64) uint64 {
+	if f == 0 {
+		// -0.0 == 0.0, but their bits differ
+		f = 0
+	}
+
+	return hashUint64(h, math.Float64bits(f))
+}
+
+func hashString(h uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= fnvPrime64
+	}
+
+	return h
+}
+
+func hashUint64(h uint64, v uint64) uint64 {
+	for i := 0; i < 8; i++ {
+		h ^= v & 0xff
+		h *= fnvPrime64
+		v >>= 8
+	}
+
+	return h
+}
+
+// Set will add an Item to the ShardedCache with the default time-to-live.
+func (s *ShardedCache[K, T]) Set(key K, value T) {
+	s.shard(key).Set(key, value)
+}
+
+// SetWithTTL will add an Item to the ShardedCache with the given time-to-live.
+func (s *ShardedCache[K, T]) SetWithTTL(key K, data T, ttl time.Duration) {
+	s.shard(key).SetWithTTL(key, data, ttl)
+}
+
+// Get returns an Item and true if the Item was found in the ShardedCache and has not been expired.
+// An empty Item and false is returned when the Item was not found or has been expired.
+func (s *ShardedCache[K, T]) Get(key K) (Item[T], bool) {
+	return s.shard(key).Get(key)
+}
+
+// Remember tries to get the Item from the ShardedCache, if the Item is not found or expired QueryFunc is called
+// to retrieve the data from source and put it into the ShardedCache.
+func (s *ShardedCache[K, T]) Remember(key K, query QueryFunc[K, T]) (Item[T], error) {
+	return s.shard(key).Remember(key, query)
+}
+
+// RememberWithTTL tries to get the Item from the ShardedCache, if the Item is not found or expired QueryFunc
+// is called to retrieve the data from source and put it into the ShardedCache with the given time-to-live.
+func (s *ShardedCache[K, T]) RememberWithTTL(key K, query QueryFunc[K, T], ttl time.Duration) (Item[T], error) {
+	return s.shard(key).RememberWithTTL(key, query, ttl)
+}
+
+// Delete removes an Item from the ShardedCache.
+func (s *ShardedCache[K, T]) Delete(key K) {
+	s.shard(key).Delete(key)
+}
+
+// Len returns the number of Items in all shards.
+// Expired Items which have not been removed by the cleanup goroutines yet are counted as well.
+func (s *ShardedCache[K, T]) Len() int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.Len()
+	}
+
+	return n
+}
+
+// Keys returns the keys of all Items in all shards which have not been expired.
+// The returned slice is a point-in-time snapshot in no particular order and may be stale immediately.
+func (s *ShardedCache[K, T]) Keys() []K {
+	var keys []K
+	for _, shard := range s.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+
+	return keys
+}
+
+// Stats returns the sum of the statistics of all shards.
+func (s *ShardedCache[K, T]) Stats() Stats {
+	var stats Stats
+	for _, shard := range s.shards {
+		st := shard.Stats()
+		stats.Hits += st.Hits
+		stats.Misses += st.Misses
+		stats.Evictions += st.Evictions
+		stats.Queries += st.Queries
+		stats.QueryDuration += st.QueryDuration
+	}
+
+	return stats
+}
+
+// Reset removes all Items from all shards.
+func (s *ShardedCache[K, T]) Reset() {
+	for _, shard := range s.shards {
+		shard.Reset()
+	}
+}
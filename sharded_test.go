@@ -0,0 +1,183 @@
+package mempot
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestShardedCache(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewShardedCache[string, string](ctx, Config{Shards: 3})
+
+	if n := len(cache.shards); n != 4 {
+		t.Errorf("got %d shards, want %d", n, 4)
+	}
+
+	want := make([]string, 0, 100)
+
+	for i := range 100 {
+		k := strconv.Itoa(i)
+		want = append(want, k)
+		cache.Set(k, data)
+	}
+
+	for _, shard := range cache.shards {
+		if shard.Len() == 0 {
+			t.Error("keys are not distributed across all shards")
+		}
+	}
+
+	keys := cache.Keys()
+	slices.Sort(keys)
+	slices.Sort(want)
+
+	if !slices.Equal(keys, want) {
+		t.Errorf("got %v, want %v", keys, want)
+	}
+
+	item, ok := cache.Get("42")
+	if !ok {
+		t.Fatal("item not found")
+	}
+
+	if item.Data != data {
+		t.Errorf("got %s, want %s", item.Data, data)
+	}
+
+	cache.Delete("42")
+
+	if _, ok = cache.Get("42"); ok {
+		t.Error("item still exists after delete")
+	}
+
+	if n := cache.Len(); n != 99 {
+		t.Errorf("got %d, want %d", n, 99)
+	}
+
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("got %+v, want %d hits and %d misses", stats, 1, 1)
+	}
+
+	cache.Reset()
+
+	if n := cache.Len(); n != 0 {
+		t.Errorf("got %d, want %d", n, 0)
+	}
+}
+
+func TestHashKey(t *testing.T) {
+	type compositeKey struct {
+		a string
+		b int
+	}
+
+	if hashKey("foo") != hashKey("foo") {
+		t.Error("hash of string key is not stable")
+	}
+
+	if hashKey(compositeKey{"foo", 1}) != hashKey(compositeKey{"foo", 1}) {
+		t.Error("hash of struct key is not stable")
+	}
+
+	if hashKey(compositeKey{"foo", 1}) == hashKey(compositeKey{"foo", 2}) {
+		t.Error("different struct keys have the same hash")
+	}
+
+	type state struct {
+		n int
+	}
+
+	ptr := &state{1}
+	h := hashKey(ptr)
+	ptr.n = 2
+
+	if hashKey(ptr) != h {
+		t.Error("hash of pointer key changed with the value it points to")
+	}
+
+	if hashKey(&state{2}) == h {
+		t.Error("different pointer keys with equal values have the same hash")
+	}
+
+	if n := testing.AllocsPerRun(100, func() { hashKey(ptr) }); n != 0 {
+		t.Errorf("got %v allocations for a pointer key, want %d", n, 0)
+	}
+
+	type floatKey struct {
+		a string
+		b float64
+	}
+
+	negZero := math.Copysign(0, -1)
+
+	if hashKey(negZero) != hashKey(0.0) {
+		t.Error("negative zero and zero have different hashes")
+	}
+
+	if hashKey(floatKey{"foo", negZero}) != hashKey(floatKey{"foo", 0}) {
+		t.Error("struct keys with negative zero and zero have different hashes")
+	}
+
+	if hashKey[any](complex(negZero, 1)) != hashKey[any](complex(0, 1)) {
+		t.Error("complex keys with negative zero and zero have different hashes")
+	}
+}
+
+func TestShardedCacheFloatKeys(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewShardedCache[float64, string](ctx, Config{Shards: 16})
+	cache.Set(0, data)
+
+	if _, ok := cache.Get(math.Copysign(0, -1)); !ok {
+		t.Error("item not found by negative zero")
+	}
+}
+
+func benchmarkParallel(b *testing.B, set func(key string), get func(key string)) {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+		set(keys[i])
+	}
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			k := keys[i%len(keys)]
+			if i%4 == 0 {
+				set(k)
+			} else {
+				get(k)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkCacheParallel(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[string, string](ctx, Config{DefaultTTL: time.Minute})
+
+	benchmarkParallel(b, func(key string) { cache.Set(key, data) }, func(key string) { cache.Get(key) })
+}
+
+func BenchmarkShardedCacheParallel(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewShardedCache[string, string](ctx, Config{DefaultTTL: time.Minute, Shards: 32})
+
+	benchmarkParallel(b, func(key string) { cache.Set(key, data) }, func(key string) { cache.Get(key) })
+}
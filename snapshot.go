@@ -0,0 +1,213 @@
+package mempot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrInvalidSnapshot is returned by Restore and LoadFromFile if the snapshot cannot be decoded.
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// snapshotItem is the serialized representation of an Item in a snapshot.
+// Data holds the value encoded as JSON or, if a custom marshal function has been set,
+// the bytes returned by it encoded as base64 JSON string.
+type snapshotItem[K comparable] struct {
+	Key  K               `json:"key"`
+	Data json.RawMessage `json:"data"`
+	TTL  int64           `json:"ttl"`
+
+	CreatedAt      int64  `json:"created_at,omitempty"`
+	LastAccessedAt int64  `json:"last_accessed_at,omitempty"`
+	Version        string `json:"version,omitempty"`
+}
+
+// SetMarshaler sets the functions used by Snapshot and Restore to encode and decode the data of the Items,
+// e.g. to use encoding/gob or protobuf for types which cannot be serialized with encoding/json.
+// The keys and the expiration of the Items are still encoded by the Cache.
+// If both functions are nil, the data is encoded with encoding/json.
+func (c *Cache[K, T]) SetMarshaler(marshal func(value T) ([]byte, error), unmarshal func(data []byte) (T, error)) {
+	c.mut.Lock()
+	c.marshal = marshal
+	c.unmarshal = unmarshal
+	c.mut.Unlock()
+}
+
+// Snapshot returns all Items of the Cache which have not been expired encoded as JSON.
+// The expiration of each Item is preserved as Unix time in nanoseconds like Item.TTL.
+// K must be serializable with encoding/json and so must be T unless a marshal function has been set
+// with SetMarshaler, otherwise an error is returned or data might be lost, e.g. for unexported struct fields.
+func (c *Cache[K, T]) Snapshot() ([]byte, error) {
+	c.mut.RLock()
+	marshal := c.marshal
+	c.mut.RUnlock()
+
+	all := c.GetAll()
+	items := make([]snapshotItem[K], 0, len(all))
+
+	for key, item := range all {
+		data, err := marshalData(marshal, item.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal data of key %v: %w", key, err)
+		}
+
+		items = append(items, snapshotItem[K]{
+			Key:            key,
+			Data:           data,
+			TTL:            item.TTL,
+			CreatedAt:      item.CreatedAt,
+			LastAccessedAt: item.LastAccessedAt,
+			Version:        item.Version,
+		})
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	return data, nil
+}
+
+// marshalData encodes the value with the marshal function or as JSON if it is nil.
+func marshalData[T any](marshal func(value T) ([]byte, error), value T) (json.RawMessage, error) {
+	if marshal == nil {
+		return json.Marshal(value)
+	}
+
+	data, err := marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(data)
+}
+
+// unmarshalData decodes the value with the unmarshal function or as JSON if it is nil.
+func unmarshalData[T any](unmarshal func(data []byte) (T, error), raw json.RawMessage) (T, error) {
+	var value T
+
+	if unmarshal == nil {
+		err := json.Unmarshal(raw, &value)
+		return value, err
+	}
+
+	var data []byte
+
+	err := json.Unmarshal(raw, &data)
+	if err != nil {
+		return value, err
+	}
+
+	return unmarshal(data)
+}
+
+// Restore adds all Items of a snapshot created by Snapshot to the Cache.
+// Items which have been expired in the meantime are skipped and existing Items with the same key are replaced.
+// K must be deserializable with encoding/json and so must be T unless an unmarshal function has been set
+// with SetMarshaler, which has to match the marshal function used to create the snapshot.
+func (c *Cache[K, T]) Restore(data []byte) error {
+	var items []snapshotItem[K]
+
+	err := json.Unmarshal(data, &items)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+
+	c.mut.RLock()
+	unmarshal := c.unmarshal
+	c.mut.RUnlock()
+
+	values := make([]T, len(items))
+
+	for n, i := range items {
+		values[n], err = unmarshalData(unmarshal, i.Data)
+		if err != nil {
+			return fmt.Errorf("%w: failed to unmarshal data of key %v: %w", ErrInvalidSnapshot, i.Key, err)
+		}
+	}
+
+	var evicted []eviction[K, T]
+
+	c.mut.Lock()
+
+	now := c.now()
+
+	for n, i := range items {
+		item := Item[T]{Data: values[n], CreatedAt: i.CreatedAt, LastAccessedAt: i.LastAccessedAt, Version: i.Version}
+
+		var ttl time.Duration
+		if i.TTL != 0 {
+			item.TTL = i.TTL
+			ttl = time.Unix(0, i.TTL).Sub(now)
+		}
+
+		if item.expiredAt(now) {
+			continue
+		}
+
+		evicted = append(evicted, c.set(i.Key, item, ttl)...)
+	}
+
+	c.mut.Unlock()
+
+	c.notifyEvicted(evicted)
+
+	return nil
+}
+
+// SaveToFile writes a snapshot of the Cache to the file at path.
+// The snapshot is written to a temporary file first which then replaces the file at path,
+// so the file is never left partially written.
+func (c *Cache[K, T]) SaveToFile(path string) error {
+	data, err := c.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	err = f.Sync()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync snapshot: %w", err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close snapshot: %w", err)
+	}
+
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return fmt.Errorf("failed to rename snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// LoadFromFile restores a snapshot of the Cache from the file at path written by SaveToFile.
+// If the file does not exist, the returned error satisfies errors.Is(err, fs.ErrNotExist).
+// If the file is corrupt, the returned error satisfies errors.Is(err, ErrInvalidSnapshot).
+func (c *Cache[K, T]) LoadFromFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	return c.Restore(data)
+}
@@ -0,0 +1,207 @@
+package mempot
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCacheSnapshotRestore(t *testing.T) {
+	cache, clock, cancel := setupFakeClockCache(Config{CleanupInterval: time.Hour})
+	defer cancel()
+
+	cache.SetWithTTLAndVersion("a", data, time.Minute, "v1")
+	cache.SetWithTTL("b", data, 0)
+	cache.SetWithTTL("c", data, time.Millisecond*50)
+	cache.SetWithTTL("d", data, time.Second)
+
+	clock.Advance(time.Millisecond * 100)
+
+	snapshot, err := cache.Snapshot()
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %s", err)
+	}
+
+	clock.Advance(time.Second)
+
+	ctx, cancelRestored := context.WithCancel(context.Background())
+	defer cancelRestored()
+
+	restored := NewCache[string, string](ctx, Config{Clock: clock})
+
+	err = restored.Restore(snapshot)
+	if err != nil {
+		t.Fatalf("failed to restore snapshot: %s", err)
+	}
+
+	keys := restored.Keys()
+	slices.Sort(keys)
+
+	if !slices.Equal(keys, []string{"a", "b"}) {
+		t.Errorf("got %v, want %v", keys, []string{"a", "b"})
+	}
+
+	if n := restored.Len(); n != 2 {
+		t.Errorf("got %d, want %d", n, 2)
+	}
+
+	original, _ := cache.Get("a")
+	item, _ := restored.Get("a")
+
+	if item != original {
+		t.Errorf("got %+v, want %+v", item, original)
+	}
+}
+
+func TestCacheRestoreInvalid(t *testing.T) {
+	cache, cancel := setupCache(1, 1)
+	defer cancel()
+
+	err := cache.Restore([]byte("{invalid"))
+	if !errors.Is(err, ErrInvalidSnapshot) {
+		t.Errorf("got %v, want %v", err, ErrInvalidSnapshot)
+	}
+}
+
+func TestCacheSaveLoadFile(t *testing.T) {
+	cache, cancel := setupCache(1, 1)
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	cache.SetWithTTL(key, data, time.Minute)
+
+	err := cache.SaveToFile(path)
+	if err != nil {
+		t.Fatalf("failed to save snapshot: %s", err)
+	}
+
+	loaded, cancelLoaded := setupCache(1, 1)
+	defer cancelLoaded()
+
+	err = loaded.LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %s", err)
+	}
+
+	item, ok := loaded.Get(key)
+	if !ok {
+		t.Fatal("item not found")
+	}
+
+	if item.Data != data {
+		t.Errorf("got %s, want %s", item.Data, data)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("failed to read directory: %s", err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("got %d files, want %d", len(entries), 1)
+	}
+}
+
+func TestCacheLoadFileErrors(t *testing.T) {
+	cache, cancel := setupCache(1, 1)
+	defer cancel()
+
+	dir := t.TempDir()
+
+	err := cache.LoadFromFile(filepath.Join(dir, "missing.json"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, fs.ErrNotExist)
+	}
+
+	path := filepath.Join(dir, "corrupt.json")
+
+	err = os.WriteFile(path, []byte(`[{"key":"foo","data":`), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+
+	err = cache.LoadFromFile(path)
+	if !errors.Is(err, ErrInvalidSnapshot) {
+		t.Errorf("got %v, want %v", err, ErrInvalidSnapshot)
+	}
+}
+
+// point cannot be serialized with encoding/json as its fields are unexported.
+type point struct {
+	x, y int
+}
+
+func (p point) GobEncode() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d,%d", p.x, p.y)), nil
+}
+
+func (p *point) GobDecode(data []byte) error {
+	_, err := fmt.Sscanf(string(data), "%d,%d", &p.x, &p.y)
+	return err
+}
+
+func gobMarshal(value point) ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := gob.NewEncoder(&buf).Encode(value)
+
+	return buf.Bytes(), err
+}
+
+func gobUnmarshal(data []byte) (point, error) {
+	var value point
+
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value)
+
+	return value, err
+}
+
+func TestCacheSnapshotRestoreMarshaler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[string, point](ctx, Config{CleanupInterval: time.Hour})
+	cache.SetMarshaler(gobMarshal, gobUnmarshal)
+
+	cache.SetWithTTL("a", point{x: 1, y: 2}, time.Minute)
+	cache.SetWithTTL("b", point{x: 3, y: 4}, 0)
+
+	snapshot, err := cache.Snapshot()
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %s", err)
+	}
+
+	restored := NewCache[string, point](ctx, Config{CleanupInterval: time.Hour})
+	restored.SetMarshaler(gobMarshal, gobUnmarshal)
+
+	err = restored.Restore(snapshot)
+	if err != nil {
+		t.Fatalf("failed to restore snapshot: %s", err)
+	}
+
+	for _, k := range []string{"a", "b"} {
+		original, _ := cache.Get(k)
+		item, _ := restored.Get(k)
+
+		if item != original {
+			t.Errorf("got %+v, want %+v", item, original)
+		}
+	}
+
+	// without the unmarshal function the data cannot be decoded
+	plain := NewCache[string, point](ctx, Config{CleanupInterval: time.Hour})
+
+	err = plain.Restore(snapshot)
+	if !errors.Is(err, ErrInvalidSnapshot) {
+		t.Errorf("got %v, want %v", err, ErrInvalidSnapshot)
+	}
+}
@@ -0,0 +1,82 @@
+package mempot
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TieredCache combines a small hot Cache, usually limited by MaxItems, with a larger cold Cache,
+// usually with a longer time-to-live. Items are written to both tiers and read from the hot tier first,
+// Items only found in the cold tier are promoted to the hot tier.
+type TieredCache[K comparable, T any] struct {
+	// mut ensures that a promotion does not overwrite an Item which has been set or deleted concurrently.
+	mut sync.RWMutex
+
+	hot  *Cache[K, T]
+	cold *Cache[K, T]
+}
+
+// NewTieredCache creates a new TieredCache instance with K as key and T as data.
+// Both tiers are created with their own Config.
+// If the context is canceled, both tiers will stop their cleanup goroutines.
+func NewTieredCache[K comparable, T any](ctx context.Context, hot, cold Config) *TieredCache[K, T] {
+	return &TieredCache[K, T]{
+		hot:  NewCache[K, T](ctx, hot),
+		cold: NewCache[K, T](ctx, cold),
+	}
+}
+
+// Set will add an Item to both tiers with their default time-to-live.
+func (t *TieredCache[K, T]) Set(key K, value T) {
+	t.mut.Lock()
+	t.hot.Set(key, value)
+	t.cold.Set(key, value)
+	t.mut.Unlock()
+}
+
+// Get returns an Item and true if the Item was found in one of the tiers and has not been expired.
+// If the Item was only found in the cold tier, it is promoted to the hot tier with the default time-to-live
+// of the hot tier, but not beyond its expiration in the cold tier.
+// An empty Item and false is returned when the Item was not found or has been expired.
+func (t *TieredCache[K, T]) Get(key K) (Item[T], bool) {
+	t.mut.RLock()
+	defer t.mut.RUnlock()
+
+	item, ok := t.hot.Get(key)
+	if ok {
+		return item, true
+	}
+
+	item, ok = t.cold.Get(key)
+	if !ok {
+		return Item[T]{}, false
+	}
+
+	ttl := t.hot.DefaultTTL()
+	if item.TTL != 0 {
+		ttl = min(ttl, time.Unix(0, item.TTL).Sub(t.cold.now()))
+	}
+
+	if ttl > 0 {
+		t.hot.SetWithTTL(key, item.Data, ttl)
+	}
+
+	return item, true
+}
+
+// Delete removes an Item from both tiers.
+func (t *TieredCache[K, T]) Delete(key K) {
+	t.mut.Lock()
+	t.hot.Delete(key)
+	t.cold.Delete(key)
+	t.mut.Unlock()
+}
+
+// Reset removes all Items from both tiers.
+func (t *TieredCache[K, T]) Reset() {
+	t.mut.Lock()
+	t.hot.Reset()
+	t.cold.Reset()
+	t.mut.Unlock()
+}
@@ -0,0 +1,120 @@
+package mempot
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTieredCache(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := &fakeClock{now: time.Now()}
+
+	cache := NewTieredCache[string, string](ctx,
+		Config{DefaultTTL: time.Minute, CleanupInterval: time.Hour, MaxItems: 2, Clock: clock},
+		Config{DefaultTTL: time.Hour, CleanupInterval: time.Hour, Clock: clock},
+	)
+
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+	cache.Set("c", "3")
+
+	// "a" has been evicted from the hot tier
+	if cache.hot.Exists("a") {
+		t.Fatal("least recently used item still exists in hot tier")
+	}
+
+	item, ok := cache.Get("a")
+	if !ok || item.Data != "1" {
+		t.Fatalf("got %+v, want %s", item, "1")
+	}
+
+	if !cache.hot.Exists("a") {
+		t.Error("cold hit has not been promoted to hot tier")
+	}
+
+	// promoted items expire in the hot tier with its default time-to-live
+	clock.Advance(time.Minute * 2)
+
+	if cache.hot.Exists("a") {
+		t.Error("promoted item has not been expired in hot tier")
+	}
+
+	if item, ok := cache.Get("a"); !ok || item.Data != "1" {
+		t.Errorf("got %+v, want %s", item, "1")
+	}
+
+	cache.Delete("a")
+
+	if _, ok := cache.Get("a"); ok {
+		t.Error("deleted item found")
+	}
+}
+
+func TestTieredCachePromotionTTL(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := &fakeClock{now: time.Now()}
+
+	cache := NewTieredCache[string, string](ctx,
+		Config{DefaultTTL: time.Hour, CleanupInterval: time.Hour, Clock: clock},
+		Config{DefaultTTL: time.Minute, CleanupInterval: time.Hour, Clock: clock},
+	)
+
+	cache.cold.Set(key, data)
+	clock.Advance(time.Second * 30)
+
+	if _, ok := cache.Get(key); !ok {
+		t.Fatal("item not found")
+	}
+
+	// the promoted item must not outlive the item in the cold tier
+	if ttl, _ := cache.hot.GetTTL(key); ttl > time.Second*30 {
+		t.Errorf("got %s, want at most %s", ttl, time.Second*30)
+	}
+}
+
+func TestTieredCacheConcurrent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewTieredCache[string, int](ctx,
+		Config{DefaultTTL: time.Minute, MaxItems: 4},
+		Config{DefaultTTL: time.Hour},
+	)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := range 100 {
+				k := strconv.Itoa(j % 10)
+				if i%2 == 0 {
+					cache.Set(k, j)
+				} else {
+					cache.Get(k)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// both tiers must hold the same value for every key
+	for j := range 10 {
+		k := strconv.Itoa(j)
+
+		hot, okHot := cache.hot.Peek(k)
+		cold, _ := cache.cold.Peek(k)
+
+		if okHot && hot.Data != cold.Data {
+			t.Errorf("got %d in hot tier and %d in cold tier for %s", hot.Data, cold.Data, k)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package mempot
+
+import "time"
+
+// Tx gives access to the Items of a Cache while the write lock is held by Cache.Atomic.
+// A Tx must not be used after the function passed to Cache.Atomic has returned.
+type Tx[K comparable, T any] struct {
+	c       *Cache[K, T]
+	evicted []eviction[K, T]
+}
+
+// Atomic calls fn with a Tx while holding the write lock, so all operations of the Tx
+// happen as one consistent unit. All other operations on the Cache are blocked until fn returns,
+// so fn should not block or do long running work and must not access the Cache other than through the Tx.
+// Callbacks for Items evicted within fn are called after fn has returned and the lock has been released.
+func (c *Cache[K, T]) Atomic(fn func(tx *Tx[K, T])) {
+	tx := &Tx[K, T]{c: c}
+
+	tx.run(fn)
+
+	c.notifyEvicted(tx.evicted)
+}
+
+// run calls fn while holding the write lock.
+// The lock is released in a defer, so a panic in fn which is recovered by the caller does not leave it held.
+func (tx *Tx[K, T]) run(fn func(tx *Tx[K, T])) {
+	tx.c.mut.Lock()
+	defer tx.c.mut.Unlock()
+
+	fn(tx)
+}
+
+// Get returns an Item and true if the Item was found in the Cache and has not been expired.
+// An empty Item and false is returned when the Item was not found or has been expired.
+func (tx *Tx[K, T]) Get(key K) (Item[T], bool) {
+	item, ok := tx.c.lookup(key, tx.c.touchOnGet())
+	if !ok {
+		tx.c.misses.Add(1)
+		return Item[T]{}, false
+	}
+
+	tx.c.hits.Add(1)
+
+	return item, true
+}
+
+// Set will add an Item to the Cache with the default time-to-live.
+func (tx *Tx[K, T]) Set(key K, value T) {
+	tx.SetWithTTL(key, value, tx.c.DefaultTTL())
+}
+
+// SetWithTTL will add an Item to the Cache with the given time-to-live.
+func (tx *Tx[K, T]) SetWithTTL(key K, value T, ttl time.Duration) {
+	tx.evicted = append(tx.evicted, tx.c.set(key, tx.c.newItem(value, ttl), ttl)...)
+}
+
+// Delete removes an Item from the Cache.
+func (tx *Tx[K, T]) Delete(key K) {
+	if tx.c.remove(key) {
+		tx.c.publish(EventDelete, key)
+	}
+}
@@ -0,0 +1,124 @@
+package mempot
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCacheAtomic(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+
+	// swap "a" and "b" if both exist
+	swap := func(tx *Tx[string, string]) {
+		a, okA := tx.Get("a")
+		b, okB := tx.Get("b")
+
+		if !okA || !okB {
+			return
+		}
+
+		tx.Set("a", b.Data)
+		tx.Set("b", a.Data)
+	}
+
+	var wg sync.WaitGroup
+	for range 11 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Atomic(swap)
+		}()
+	}
+
+	// concurrent readers must never observe a state where "a" and "b" are equal
+	for range 100 {
+		var a, b Item[string]
+
+		cache.Atomic(func(tx *Tx[string, string]) {
+			a, _ = tx.Get("a")
+			b, _ = tx.Get("b")
+		})
+
+		if a.Data == b.Data {
+			t.Fatalf("got %s for both items, want different values", a.Data)
+		}
+	}
+
+	wg.Wait()
+
+	a, _ := cache.Get("a")
+	b, _ := cache.Get("b")
+
+	// an odd number of swaps has been made
+	if a.Data != "2" || b.Data != "1" {
+		t.Errorf("got %s and %s, want %s and %s", a.Data, b.Data, "2", "1")
+	}
+
+	cache.Atomic(func(tx *Tx[string, string]) {
+		tx.Delete("a")
+
+		if _, ok := tx.Get("a"); ok {
+			t.Error("deleted item found")
+		}
+
+		tx.SetWithTTL("c", "3", time.Minute)
+	})
+
+	if cache.Exists("a") || !cache.Exists("c") {
+		t.Errorf("got keys %v, want %v", cache.Keys(), []string{"b", "c"})
+	}
+}
+
+func TestCacheAtomicEvicted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache[string, string](ctx, Config{MaxItems: 2})
+
+	var evicted []string
+	cache.OnEvict(func(key string, value string) {
+		// the lock has been released
+		cache.Len()
+		evicted = append(evicted, key)
+	})
+
+	cache.Atomic(func(tx *Tx[string, string]) {
+		for i := range 4 {
+			tx.Set(strconv.Itoa(i), data)
+		}
+	})
+
+	if len(evicted) != 2 || evicted[0] != "0" || evicted[1] != "1" {
+		t.Errorf("got %v, want %v", evicted, []string{"0", "1"})
+	}
+}
+
+func TestCacheAtomicPanic(t *testing.T) {
+	cache, cancel := setupCache(60, 60)
+	defer cancel()
+
+	func() {
+		defer func() {
+			if r := recover(); r != "tx failed" {
+				t.Errorf("got %v, want %q", r, "tx failed")
+			}
+		}()
+
+		cache.Atomic(func(tx *Tx[string, string]) {
+			tx.Set(key, data)
+			panic("tx failed")
+		})
+	}()
+
+	if !cache.mut.TryLock() {
+		t.Fatal("lock is still held after a panic in fn")
+	}
+	cache.mut.Unlock()
+}